@@ -0,0 +1,536 @@
+// Local JSON REST API so budgie can be scripted alongside the TUI.
+// Started with `budgie serve`.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const default_api_addr = "127.0.0.1:8080"
+const default_api_per_page = 50
+const max_api_per_page = 500
+
+// Keeps the number of expenses skipped well within int64
+const max_api_page = 1000000
+const max_api_upload_size = 10 << 20 // 10 MB
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type apiExpensePage struct {
+	Expenses []Expense `json:"expenses"`
+	Page     int64     `json:"page"`
+	PerPage  int64     `json:"per_page"`
+	Total    int64     `json:"total"`
+}
+
+type apiImportResult struct {
+	Inserted int       `json:"inserted"`
+	Rejected int       `json:"rejected"`
+	Expenses []Expense `json:"expenses"`
+}
+
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", default_api_addr, "address to listen on")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	log.Printf("budgie API listening on http://%s", *addr)
+	return http.ListenAndServe(*addr, newAPIHandler(mongoExpenseStore{}))
+}
+
+// Storage behind the expense handlers, so they can be tested without a
+// database
+type expenseStore interface {
+	countMatching(search entrySearch) (int64, error)
+	findPage(search entrySearch, skip int64, limit int64) ([]Expense, error)
+	findByID(id primitive.ObjectID) (Expense, error) // mongo.ErrNoDocuments if missing
+	insert(entry Expense) (primitive.ObjectID, error)
+	updateByID(id primitive.ObjectID, entry Expense) (bool, error)
+	deleteByID(id primitive.ObjectID) (bool, error)
+	insertValid(entries []Expense) (int, error)
+
+	// parses an uploaded statement with the named import profile, errors
+	// are the client's
+	parseUpload(filename string, data []byte, profile string) ([]Expense, error)
+}
+
+type mongoExpenseStore struct{}
+
+func (mongoExpenseStore) countMatching(search entrySearch) (int64, error) {
+	return mongoCountMatchingEntries(search)
+}
+
+func (mongoExpenseStore) findPage(search entrySearch, skip int64, limit int64) ([]Expense, error) {
+	return mongoFindEntriesPage(search, skip, limit)
+}
+
+func (mongoExpenseStore) findByID(id primitive.ObjectID) (Expense, error) {
+	return mongoFindEntryByID(id)
+}
+
+func (mongoExpenseStore) insert(entry Expense) (primitive.ObjectID, error) {
+	return mongoInsertEntry(entry)
+}
+
+func (mongoExpenseStore) updateByID(id primitive.ObjectID, entry Expense) (bool, error) {
+	return mongoUpdateEntryByID(id, entry)
+}
+
+func (mongoExpenseStore) deleteByID(id primitive.ObjectID) (bool, error) {
+	return mongoDeleteEntryByID(id)
+}
+
+func (mongoExpenseStore) insertValid(entries []Expense) (int, error) {
+	return mongoInsertValidEntries(entries)
+}
+
+func (mongoExpenseStore) parseUpload(filename string, data []byte, profile_name string) ([]Expense, error) {
+	profile, err := findImportProfile(profile_name)
+	if err != nil {
+		return nil, err
+	}
+	return parseStatement(filename, data, profile)
+}
+
+type apiServer struct {
+	store expenseStore
+}
+
+func newAPIHandler(store expenseStore) http.Handler {
+	api := apiServer{store: store}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /expenses", api.listExpenses)
+	mux.HandleFunc("POST /expenses", api.createExpense)
+	mux.HandleFunc("GET /expenses/{id}", api.getExpense)
+	mux.HandleFunc("PUT /expenses/{id}", api.updateExpense)
+	mux.HandleFunc("DELETE /expenses/{id}", api.deleteExpense)
+	mux.HandleFunc("POST /imports", api.importStatement)
+	mux.HandleFunc("GET /forecast", apiForecast)
+	mux.HandleFunc("GET /reports/forecast", webForecastReport)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("error writing response: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, apiError{Error: msg})
+}
+
+func writeStorageError(w http.ResponseWriter, err error) {
+	log.Printf("storage error: %v", err)
+	writeAPIError(w, http.StatusInternalServerError, "storage error")
+}
+
+// GET /expenses?year=&month=&day=&description=&desc_regex=&debit=&credit=&from=&to=
+// &min_debit=&max_debit=&min_credit=&max_credit=&min_amount=&max_amount=&q=&sort=&page=&per_page=
+func (api apiServer) listExpenses(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	search, err := parseSearch(query.Get)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := parsePositiveParam(query.Get("page"), 1)
+	if err != nil || page > max_api_page {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("page must be between 1 and %d", max_api_page))
+		return
+	}
+	per_page, err := parsePositiveParam(query.Get("per_page"), default_api_per_page)
+	if err != nil || per_page > max_api_per_page {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("per_page must be between 1 and %d", max_api_per_page))
+		return
+	}
+
+	total, err := api.store.countMatching(search)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	expenses, err := api.store.findPage(search, (page-1)*per_page, per_page)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiExpensePage{
+		Expenses: expenses,
+		Page:     page,
+		PerPage:  per_page,
+		Total:    total,
+	})
+}
+
+// GET /expenses/{id}
+func (api apiServer) getExpense(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseAPIExpenseID(w, r)
+	if !ok {
+		return
+	}
+
+	expense, err := api.store.findByID(id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeAPIError(w, http.StatusNotFound, "expense not found")
+		return
+	} else if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, expense)
+}
+
+// POST /expenses
+func (api apiServer) createExpense(w http.ResponseWriter, r *http.Request) {
+	entry, ok := decodeAPIExpense(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := api.store.insert(entry)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	entry.ID = id
+
+	w.Header().Set("Location", "/expenses/"+id.Hex())
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+// PUT /expenses/{id}
+func (api apiServer) updateExpense(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseAPIExpenseID(w, r)
+	if !ok {
+		return
+	}
+
+	entry, ok := decodeAPIExpense(w, r)
+	if !ok {
+		return
+	}
+
+	found, err := api.store.updateByID(id, entry)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	} else if !found {
+		writeAPIError(w, http.StatusNotFound, "expense not found")
+		return
+	}
+
+	// the stored expense, with the fields the body cannot set
+	stored, err := api.store.findByID(id)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stored)
+}
+
+// DELETE /expenses/{id}
+func (api apiServer) deleteExpense(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseAPIExpenseID(w, r)
+	if !ok {
+		return
+	}
+
+	found, err := api.store.deleteByID(id)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	} else if !found {
+		writeAPIError(w, http.StatusNotFound, "expense not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /forecast
+func apiForecast(w http.ResponseWriter, r *http.Request) {
+	forecasts, err := buildForecast(time.Now())
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, forecasts)
+}
+
+// POST /imports
+// Accepts either a multipart form with a "file" field or a raw body. The
+// statement format is picked from the uploaded filename, or for raw bodies
+// from the "filename" query parameter (csv if missing). The "profile" query
+// parameter selects a csv layout from the config file.
+func (api apiServer) importStatement(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, max_api_upload_size)
+
+	var data []byte
+	var err error
+	filename := r.URL.Query().Get("filename")
+
+	media_type, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if media_type == "multipart/form-data" {
+		file, header, ferr := r.FormFile("file")
+		if ferr != nil {
+			writeAPIError(w, http.StatusBadRequest, "missing file field")
+			return
+		}
+		defer file.Close()
+		filename = header.Filename
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "could not read upload")
+		return
+	}
+	if len(data) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "empty upload")
+		return
+	}
+
+	entries, err := api.store.parseUpload(filename, data, r.URL.Query().Get("profile"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	inserted, err := api.store.insertValid(entries)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, apiImportResult{
+		Inserted: inserted,
+		Rejected: len(entries) - inserted,
+		Expenses: entries,
+	})
+}
+
+func parseAPIExpenseID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid expense id")
+		return id, false
+	}
+	return id, true
+}
+
+// Decodes and validates an expense from the request body
+func decodeAPIExpense(w http.ResponseWriter, r *http.Request) (Expense, bool) {
+	entry := Expense{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&entry); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return entry, false
+	}
+
+	entry.Valid = false
+	entry.Attachments = nil // files are attached with budgie attach or in the TUI
+	checkValidEntryValues(&entry)
+	if !entry.Valid {
+		writeAPIError(w, http.StatusUnprocessableEntity, "a date that exists (year, month, day), description and a debit or credit are required")
+		return entry, false
+	}
+
+	return entry, true
+}
+
+// Builds an Expense to search for using the same rules as the find entry screen.
+// Missing parameters match everything.
+func parseAPISearch(get func(string) string) (Expense, error) {
+	entry := Expense{
+		Month:  invalid,
+		Day:    invalid,
+		Year:   invalid,
+		Debit:  invalid,
+		Credit: invalid,
+	}
+
+	if s := get("year"); s != "" {
+		year, err := strconv.Atoi(s)
+		if err != nil {
+			return entry, errors.New("invalid year")
+		}
+		entry.Year = year
+	}
+	if s := get("month"); s != "" {
+		month, err := parseMonth(s)
+		if err != nil {
+			return entry, err
+		}
+		entry.Month = month
+	}
+	if s := get("day"); s != "" {
+		day, err := strconv.Atoi(s)
+		if err != nil || day < 1 || day > 31 {
+			return entry, errors.New("invalid day, must be between 1 and 31")
+		}
+		entry.Day = day
+	}
+	entry.Description = get("description")
+	if s := get("debit"); s != "" {
+		val, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return entry, errors.New("invalid debit amount")
+		}
+		entry.Debit = val
+	}
+	if s := get("credit"); s != "" {
+		val, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return entry, errors.New("invalid credit amount")
+		}
+		entry.Credit = val
+	}
+
+	return entry, nil
+}
+
+// Builds a search from the parameters of parseAPISearch, the from and to
+// dates, amount ranges and a query. Missing parameters match everything.
+func parseSearch(get func(string) string) (entrySearch, error) {
+	search := entrySearch{}
+
+	entry, err := parseAPISearch(get)
+	if err != nil {
+		return search, err
+	}
+	search.entry = entry
+
+	search.date_range, err = parseOptionalPeriod(get("from"), get("to"))
+	if err != nil {
+		return search, err
+	}
+
+	search.debit_range, err = parseAmountRange(get("min_debit"), get("max_debit"), "debit")
+	if err != nil {
+		return search, err
+	}
+	search.credit_range, err = parseAmountRange(get("min_credit"), get("max_credit"), "credit")
+	if err != nil {
+		return search, err
+	}
+	search.amount_range, err = parseAmountRange(get("min_amount"), get("max_amount"), "amount")
+	if err != nil {
+		return search, err
+	}
+
+	search.desc_regex = get("desc_regex")
+	if _, err := regexp.Compile(search.desc_regex); err != nil {
+		return search, errors.New("invalid desc_regex: " + err.Error())
+	}
+
+	search.query, err = parseQuery(get("q"))
+	if err != nil {
+		return search, errors.New("invalid query " + err.Error())
+	}
+
+	// text: searches are ranked by relevance unless sorted otherwise
+	if get("sort") == "" && hasTextTerm(search.query) {
+		search.sort.column = sort_relevance
+		return search, nil
+	}
+	search.sort, err = parseEntrySort(get("sort"))
+	return search, err
+}
+
+// A column name, prefixed with - to sort descending, e.g. -debit
+func parseEntrySort(s string) (entrySort, error) {
+	sort := entrySort{}
+	if s == "" {
+		return sort, nil
+	}
+
+	name, descending := strings.CutPrefix(s, "-")
+	for column, column_name := range sort_column_names {
+		if name == column_name {
+			sort.column = column
+			sort.descending = descending
+			return sort, nil
+		}
+	}
+	return sort, errors.New("invalid sort, expected date, description, debit, credit, category or relevance")
+}
+
+// Either end may be blank to leave it open
+func parseAmountRange(min string, max string, name string) (amountRange, error) {
+	r := amountRange{}
+	if min != "" {
+		val, err := strconv.ParseFloat(min, 64)
+		if err != nil {
+			return r, errors.New("invalid minimum " + name)
+		}
+		r.min, r.has_min = val, true
+	}
+	if max != "" {
+		val, err := strconv.ParseFloat(max, 64)
+		if err != nil {
+			return r, errors.New("invalid maximum " + name)
+		}
+		r.max, r.has_max = val, true
+	}
+	if r.has_min && r.has_max && r.max < r.min {
+		return r, errors.New("maximum " + name + " is less than the minimum")
+	}
+	return r, nil
+}
+
+// Accepts Jan, Feb, Mar, etc. or a month number
+func parseMonth(s string) (int, error) {
+	month, err := time.Parse("Jan", s)
+	if err == nil {
+		return int(month.Month()), nil
+	}
+
+	num, err := strconv.Atoi(s)
+	if err == nil && num >= 1 && num <= 12 {
+		return num, nil
+	}
+
+	return 0, errors.New("invalid month, format: Jan, Feb, Mar, etc.")
+}
+
+func parsePositiveParam(s string, default_value int64) (int64, error) {
+	if strings.TrimSpace(s) == "" {
+		return default_value, nil
+	}
+	val, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || val < 1 {
+		return 0, errors.New("must be a positive integer")
+	}
+	return val, nil
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// In-memory expenseStore. Searches are not evaluated, every stored
+// expense matches; the last search and page bounds are recorded instead.
+type memoryExpenseStore struct {
+	expenses    []Expense
+	last_search entrySearch
+	last_skip   int64
+	last_limit  int64
+}
+
+func (s *memoryExpenseStore) indexOf(id primitive.ObjectID) int {
+	for idx, entry := range s.expenses {
+		if entry.ID == id {
+			return idx
+		}
+	}
+	return -1
+}
+
+func (s *memoryExpenseStore) countMatching(search entrySearch) (int64, error) {
+	s.last_search = search
+	return int64(len(s.expenses)), nil
+}
+
+func (s *memoryExpenseStore) findPage(search entrySearch, skip int64, limit int64) ([]Expense, error) {
+	s.last_search, s.last_skip, s.last_limit = search, skip, limit
+	page := []Expense{}
+	for idx := skip; idx < skip+limit && idx < int64(len(s.expenses)); idx++ {
+		page = append(page, s.expenses[idx])
+	}
+	return page, nil
+}
+
+func (s *memoryExpenseStore) findByID(id primitive.ObjectID) (Expense, error) {
+	if idx := s.indexOf(id); idx >= 0 {
+		return s.expenses[idx], nil
+	}
+	return Expense{}, mongo.ErrNoDocuments
+}
+
+func (s *memoryExpenseStore) insert(entry Expense) (primitive.ObjectID, error) {
+	entry.ID = primitive.NewObjectID()
+	s.expenses = append(s.expenses, entry)
+	return entry.ID, nil
+}
+
+func (s *memoryExpenseStore) updateByID(id primitive.ObjectID, entry Expense) (bool, error) {
+	idx := s.indexOf(id)
+	if idx < 0 {
+		return false, nil
+	}
+	// attachments are only changed with budgie attach or in the TUI
+	entry.ID = id
+	entry.Attachments = s.expenses[idx].Attachments
+	s.expenses[idx] = entry
+	return true, nil
+}
+
+func (s *memoryExpenseStore) deleteByID(id primitive.ObjectID) (bool, error) {
+	idx := s.indexOf(id)
+	if idx < 0 {
+		return false, nil
+	}
+	s.expenses = append(s.expenses[:idx], s.expenses[idx+1:]...)
+	return true, nil
+}
+
+func (s *memoryExpenseStore) insertValid(entries []Expense) (int, error) {
+	inserted := 0
+	for _, entry := range entries {
+		if entry.Valid {
+			s.insert(entry)
+			inserted++
+		}
+	}
+	return inserted, nil
+}
+
+// Only the default profile exists
+func (s *memoryExpenseStore) parseUpload(filename string, data []byte, profile string) ([]Expense, error) {
+	if profile != "" {
+		return nil, errors.New("unknown import profile " + profile)
+	}
+	return parseStatement(filename, data, defaultImportProfile())
+}
+
+func newTestStore(n int) *memoryExpenseStore {
+	store := &memoryExpenseStore{}
+	for idx := 0; idx < n; idx++ {
+		store.insert(Expense{Year: 2024, Month: 7, Day: idx%28 + 1, Description: "COFFEE", Debit: float64(idx + 1), Valid: true})
+	}
+	return store
+}
+
+func serveAPI(store expenseStore, method string, target string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	newAPIHandler(store).ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAPIListExpensesPages(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		status     int
+		want_count int
+		want_skip  int64
+		want_limit int64
+	}{
+		{name: "defaults", query: "", status: http.StatusOK, want_count: 50, want_skip: 0, want_limit: default_api_per_page},
+		{name: "second page", query: "?page=2&per_page=20", status: http.StatusOK, want_count: 20, want_skip: 20, want_limit: 20},
+		{name: "last partial page", query: "?page=3&per_page=25", status: http.StatusOK, want_count: 10, want_skip: 50, want_limit: 25},
+		{name: "past the end", query: "?page=9&per_page=25", status: http.StatusOK, want_count: 0, want_skip: 200, want_limit: 25},
+		{name: "largest page", query: "?per_page=500", status: http.StatusOK, want_count: 60, want_skip: 0, want_limit: max_api_per_page},
+		{name: "page zero", query: "?page=0", status: http.StatusBadRequest},
+		{name: "negative page", query: "?page=-1", status: http.StatusBadRequest},
+		{name: "page not a number", query: "?page=two", status: http.StatusBadRequest},
+		{name: "last page allowed", query: "?page=1000000&per_page=500", status: http.StatusOK, want_count: 0, want_skip: 499999500, want_limit: 500},
+		{name: "page too large", query: "?page=1000001", status: http.StatusBadRequest},
+		{name: "page that would overflow the skip", query: "?page=9223372036854775807&per_page=500", status: http.StatusBadRequest},
+		{name: "page beyond int64", query: "?page=9223372036854775808", status: http.StatusBadRequest},
+		{name: "per_page zero", query: "?per_page=0", status: http.StatusBadRequest},
+		{name: "per_page too large", query: "?per_page=501", status: http.StatusBadRequest},
+		{name: "invalid search", query: "?month=13", status: http.StatusBadRequest},
+		{name: "invalid query", query: "?q=debit>abc", status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newTestStore(60)
+			recorder := serveAPI(store, http.MethodGet, "/expenses"+test.query, "")
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+
+			page := apiExpensePage{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Expenses) != test.want_count || page.Total != 60 {
+				t.Errorf("got %d expenses of %d, want %d of 60", len(page.Expenses), page.Total, test.want_count)
+			}
+			if store.last_skip != test.want_skip || store.last_limit != test.want_limit {
+				t.Errorf("skip, limit = %d, %d, want %d, %d", store.last_skip, store.last_limit, test.want_skip, test.want_limit)
+			}
+		})
+	}
+}
+
+func TestAPIListExpensesSearch(t *testing.T) {
+	store := newTestStore(1)
+	recorder := serveAPI(store, http.MethodGet, "/expenses?year=2024&month=Jul&description=tim&min_debit=5&sort=-debit", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	search := store.last_search
+	if search.entry.Year != 2024 || search.entry.Month != 7 || search.entry.Description != "tim" {
+		t.Errorf("entry = %+v", search.entry)
+	}
+	if !search.debit_range.has_min || search.debit_range.min != 5 || search.debit_range.has_max {
+		t.Errorf("debit range = %+v", search.debit_range)
+	}
+	if search.sort.column != sort_debit || !search.sort.descending {
+		t.Errorf("sort = %+v", search.sort)
+	}
+}
+
+func TestAPIGetExpense(t *testing.T) {
+	store := newTestStore(3)
+	id := store.expenses[1].ID
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{name: "found", target: "/expenses/" + id.Hex(), status: http.StatusOK},
+		{name: "not found", target: "/expenses/" + primitive.NewObjectID().Hex(), status: http.StatusNotFound},
+		{name: "invalid id", target: "/expenses/abc", status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serveAPI(store, http.MethodGet, test.target, "")
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			expense := Expense{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &expense); err != nil {
+				t.Fatal(err)
+			}
+			if expense.ID != id || expense.Debit != 2 {
+				t.Errorf("got %+v", expense)
+			}
+		})
+	}
+}
+
+func TestAPICreateExpense(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "valid", body: `{"year":2024,"month":7,"day":15,"description":"TIM HORTONS","debit":4.5,"notes":"team coffee"}`, status: http.StatusCreated},
+		{name: "credit only", body: `{"year":2024,"month":7,"day":1,"description":"PAYROLL","credit":2000}`, status: http.StatusCreated},
+		{name: "day that does not exist", body: `{"year":2024,"month":2,"day":30,"description":"RENT","debit":1200}`, status: http.StatusUnprocessableEntity},
+		{name: "no description", body: `{"year":2024,"month":7,"day":15,"debit":4.5}`, status: http.StatusUnprocessableEntity},
+		{name: "no amount", body: `{"year":2024,"month":7,"day":15,"description":"TIM HORTONS"}`, status: http.StatusUnprocessableEntity},
+		{name: "unknown field", body: `{"year":2024,"month":7,"day":15,"description":"X","debit":1,"amount":1}`, status: http.StatusBadRequest},
+		{name: "not JSON", body: `year=2024`, status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newTestStore(0)
+			recorder := serveAPI(store, http.MethodPost, "/expenses", test.body)
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if test.status != http.StatusCreated {
+				if len(store.expenses) != 0 {
+					t.Errorf("stored %d expenses from a rejected request", len(store.expenses))
+				}
+				return
+			}
+
+			if len(store.expenses) != 1 {
+				t.Fatalf("stored %d expenses, want 1", len(store.expenses))
+			}
+			if location := recorder.Header().Get("Location"); location != "/expenses/"+store.expenses[0].ID.Hex() {
+				t.Errorf("Location = %q", location)
+			}
+		})
+	}
+}
+
+func TestAPICreateExpenseIgnoresAttachments(t *testing.T) {
+	store := newTestStore(0)
+	body := `{"year":2024,"month":7,"day":15,"description":"X","debit":1,"attachments":[{"name":"a.pdf","hash":"ab","size":1}]}`
+	recorder := serveAPI(store, http.MethodPost, "/expenses", body)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+	if len(store.expenses[0].Attachments) != 0 {
+		t.Errorf("attachments stored from the request body: %+v", store.expenses[0].Attachments)
+	}
+}
+
+func TestAPIUpdateExpense(t *testing.T) {
+	valid := `{"year":2024,"month":7,"day":20,"description":"UPDATED","debit":9}`
+
+	tests := []struct {
+		name    string
+		missing bool
+		body    string
+		status  int
+	}{
+		{name: "valid", body: valid, status: http.StatusOK},
+		{name: "not found", missing: true, body: valid, status: http.StatusNotFound},
+		{name: "invalid", body: `{"year":2024,"month":7,"day":32,"description":"X","debit":9}`, status: http.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newTestStore(2)
+			id := store.expenses[0].ID
+			if test.missing {
+				id = primitive.NewObjectID()
+			}
+
+			recorder := serveAPI(store, http.MethodPut, "/expenses/"+id.Hex(), test.body)
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if test.status == http.StatusOK && store.expenses[0].Description != "UPDATED" {
+				t.Errorf("stored %+v", store.expenses[0])
+			}
+		})
+	}
+}
+
+func TestAPIUpdateExpenseReturnsStored(t *testing.T) {
+	store := newTestStore(1)
+	store.expenses[0].Attachments = []attachment{{Name: "receipt.pdf", Hash: "ab12", Size: 10}}
+	id := store.expenses[0].ID
+
+	body := `{"year":2024,"month":7,"day":20,"description":"UPDATED","debit":9,"category":"Dining","account":"visa","notes":"lunch"}`
+	recorder := serveAPI(store, http.MethodPut, "/expenses/"+id.Hex(), body)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	expense := Expense{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &expense); err != nil {
+		t.Fatal(err)
+	}
+	if expense.ID != id || expense.Category != "Dining" || expense.Account != "visa" || expense.Notes != "lunch" {
+		t.Errorf("got %+v", expense)
+	}
+	if len(expense.Attachments) != 1 {
+		t.Errorf("attachments = %+v, want the stored one", expense.Attachments)
+	}
+}
+
+func TestAPIDeleteExpense(t *testing.T) {
+	store := newTestStore(2)
+	id := store.expenses[0].ID
+
+	if recorder := serveAPI(store, http.MethodDelete, "/expenses/"+id.Hex(), ""); recorder.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+	if len(store.expenses) != 1 {
+		t.Fatalf("%d expenses left, want 1", len(store.expenses))
+	}
+	if recorder := serveAPI(store, http.MethodDelete, "/expenses/"+id.Hex(), ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("deleting again: status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+	if recorder := serveAPI(store, http.MethodDelete, "/expenses/xyz", ""); recorder.Code != http.StatusBadRequest {
+		t.Errorf("invalid id: status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAPIImportStatement(t *testing.T) {
+	csv := string(readFixture(t, "jan.csv"))
+	ofx := string(readFixture(t, "jul.ofx"))
+
+	tests := []struct {
+		name          string
+		target        string
+		body          string
+		status        int
+		want_inserted int
+		want_rejected int
+	}{
+		{name: "csv", target: "/imports", body: csv, status: http.StatusCreated, want_inserted: 4, want_rejected: 1},
+		{name: "ofx by filename", target: "/imports?filename=jul.ofx", body: ofx, status: http.StatusCreated, want_inserted: 4},
+		{name: "malformed csv", target: "/imports", body: "Date,Description\n07/02/2024,\"TIM \"HORTONS\",4.50\n", status: http.StatusBadRequest},
+		{name: "not the named format", target: "/imports?filename=jul.ofx", body: csv, status: http.StatusBadRequest},
+		{name: "unknown profile", target: "/imports?profile=nope", body: csv, status: http.StatusBadRequest},
+		{name: "empty body", target: "/imports", body: "", status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newTestStore(0)
+			recorder := serveAPI(store, http.MethodPost, test.target, test.body)
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if test.status != http.StatusCreated {
+				if len(store.expenses) != 0 {
+					t.Errorf("stored %d expenses from a rejected upload", len(store.expenses))
+				}
+				return
+			}
+
+			result := apiImportResult{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Inserted != test.want_inserted || result.Rejected != test.want_rejected || len(store.expenses) != test.want_inserted {
+				t.Errorf("inserted %d, rejected %d, stored %d, want %d, %d", result.Inserted, result.Rejected, len(store.expenses), test.want_inserted, test.want_rejected)
+			}
+		})
+	}
+}
+
+func TestAPIImportStatementMultipart(t *testing.T) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "jul.qif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(readFixture(t, "jul.qif"))
+	form.Close()
+
+	store := newTestStore(0)
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/imports", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	newAPIHandler(store).ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+	if len(store.expenses) != 4 || store.expenses[0].Description != "GOOGLE *Audible" {
+		t.Errorf("stored %+v", store.expenses)
+	}
+}
@@ -0,0 +1,243 @@
+// Receipts and other files attached to expenses. Files are copied into
+// a managed directory under their sha256 content hash, so attaching the
+// same receipt twice stores it once and renaming the original does not
+// break the link.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Images and PDFs, by lowercase extension
+var attachment_extensions = map[string]bool{
+	".pdf":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".webp": true,
+	".heic": true,
+}
+
+func isAttachmentFile(name string) bool {
+	return attachment_extensions[strings.ToLower(filepath.Ext(name))]
+}
+
+type attachment struct {
+	Name string `bson:"name" json:"name"` // file name when it was attached
+	Hash string `bson:"hash" json:"hash"` // sha256 of the contents
+	Size int64  `bson:"size" json:"size"`
+}
+
+// Path of the stored copy relative to the attachments directory,
+// e.g. 3f/3fa2...e1.pdf
+func (a attachment) file() string {
+	return filepath.Join(a.Hash[:2], a.Hash+strings.ToLower(filepath.Ext(a.Name)))
+}
+
+// attachments_dir from the config file, or attachments next to it
+func attachmentsDir() (string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if cfg.AttachmentsDir != "" {
+		return expandHome(cfg.AttachmentsDir), nil
+	}
+
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "attachments"), nil
+}
+
+// Full paths of the stored copies of the entry's attachments, none if
+// dir is unknown
+func attachmentPaths(dir string, entry Expense) []string {
+	paths := []string{}
+	if dir == "" {
+		return paths
+	}
+	for _, a := range entry.Attachments {
+		paths = append(paths, filepath.Join(dir, a.file()))
+	}
+	return paths
+}
+
+// Copies the file into dir unless a file with the same contents is
+// already stored
+func storeAttachment(dir string, path string) (attachment, error) {
+	name := filepath.Base(path)
+	if !isAttachmentFile(name) {
+		return attachment{}, fmt.Errorf("%s: only images and PDF files can be attached", name)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return attachment{}, err
+	}
+	a := attachment{Name: name, Hash: fileChecksum(data), Size: int64(len(data))}
+
+	stored := filepath.Join(dir, a.file())
+	if _, err := os.Stat(stored); err == nil {
+		return a, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(stored), 0o755); err != nil {
+		return a, err
+	}
+
+	// written under a temporary name so a partial copy is never mistaken
+	// for the stored file
+	tmp, err := os.CreateTemp(filepath.Dir(stored), ".attach-*")
+	if err != nil {
+		return a, err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return a, err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return a, err
+	}
+	return a, os.Rename(tmp.Name(), stored)
+}
+
+// Opens the stored copy with the desktop's default viewer
+func openAttachment(a attachment) error {
+	dir, err := attachmentsDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, a.file())
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", path)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	default:
+		cmd = exec.Command("xdg-open", path)
+	}
+	return cmd.Start()
+}
+
+// Parses the expense ID given as the first argument of attach, detach
+// and note
+func parseEntryIDArg(positional []string) (primitive.ObjectID, error) {
+	if len(positional) == 0 {
+		return primitive.NilObjectID, usageError{errors.New("expected an expense id")}
+	}
+	id, err := primitive.ObjectIDFromHex(positional[0])
+	if err != nil {
+		return id, usageError{fmt.Errorf("invalid expense id %q", positional[0])}
+	}
+	return id, nil
+}
+
+// Prints the expense after a change, or returns errNothingFound if the
+// change found no expense with the ID
+func writeChangedEntry(id primitive.ObjectID, found bool) error {
+	if !found {
+		return errNothingFound
+	}
+	entry, err := mongoFindEntryByID(id)
+	if err != nil {
+		return err
+	}
+	return writeJSONOutput(entry)
+}
+
+// `budgie attach <id> <file>...` attaches receipts to an expense
+func runAttach(args []string) error {
+	flags := flag.NewFlagSet("attach", flag.ContinueOnError)
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseEntryIDArg(positional)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 2 {
+		return usageError{errors.New("expected at least one file to attach")}
+	}
+
+	dir, err := attachmentsDir()
+	if err != nil {
+		return err
+	}
+	attachments := []attachment{}
+	for _, path := range positional[1:] {
+		a, err := storeAttachment(dir, expandHome(path))
+		if err != nil {
+			return err
+		}
+		attachments = append(attachments, a)
+	}
+
+	found, err := mongoAddEntryAttachments(id, attachments)
+	if err != nil {
+		return err
+	}
+	return writeChangedEntry(id, found)
+}
+
+// `budgie detach <id> <name|hash>` removes an attachment from an expense.
+// The stored file is kept, other expenses may share it.
+func runDetach(args []string) error {
+	flags := flag.NewFlagSet("detach", flag.ContinueOnError)
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseEntryIDArg(positional)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return usageError{errors.New("expected the name or hash of the attachment to remove")}
+	}
+
+	found, err := mongoRemoveEntryAttachment(id, positional[1])
+	if err != nil {
+		return err
+	}
+	return writeChangedEntry(id, found)
+}
+
+// `budgie note <id> [text]` sets the notes of an expense, no text
+// removes them
+func runNote(args []string) error {
+	flags := flag.NewFlagSet("note", flag.ContinueOnError)
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseEntryIDArg(positional)
+	if err != nil {
+		return err
+	}
+
+	found, err := mongoSetEntryNotes(id, strings.Join(positional[1:], " "))
+	if err != nil {
+		return err
+	}
+	return writeChangedEntry(id, found)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -11,6 +12,18 @@ const MongoDb = "budgie"
 const MongoCollection = "expenses"
 const MongoUri = "mongodb://127.0.0.1:27017" // running this on localhost
 
+// checksums of statement files that were already imported
+const MongoImportsCollection = "imported_files"
+
+// templates of scheduled entries such as rent
+const MongoRecurringCollection = "recurring"
+
+// find criteria saved under a name
+const MongoSavedSearchesCollection = "saved_searches"
+
+// version of the last storage migration
+const MongoSchemaCollection = "schema"
+
 // other constants
 const default_feedback = "Press Ctrl+C to go back to home screen."
 const num_expense_search_fields = expense_credit + 1
@@ -19,7 +32,25 @@ const num_entries_per_page = 10
 
 func main() {
 
-	p := tea.NewProgram(createHomeScreenModel())
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
+	// before the first screen, so no search runs against entries without
+	// a date
+	invalid_dates, err := prepareStorage()
+	if err != nil {
+		fmt.Printf("Error preparing storage: %v\n", err)
+		os.Exit(1)
+	}
+
+	home := createHomeScreenModel()
+	if invalid_dates > 0 {
+		home = addHomeFeedback(home, strconv.Itoa(invalid_dates)+
+			" entries have dates that do not exist, run budgie migrate to list them.")
+	}
+
+	p := tea.NewProgram(home)
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const max_import_workers = 4
+
+// Outcome of importing one statement file
+type fileImportResult struct {
+	Filename   string    `json:"file"`
+	Checksum   string    `json:"checksum"`
+	Skipped    bool      `json:"skipped"` // same contents were imported before
+	Inserted   int       `json:"inserted"`
+	Rejected   int       `json:"rejected"`
+	Duplicates int       `json:"duplicates"` // rejected because their FITID is already stored
+	Error      string    `json:"error,omitempty"`
+	Expenses   []Expense `json:"expenses"`
+}
+
+// Serializes commits so imports running at the same time cannot both
+// insert a transaction
+var import_insert_lock sync.Mutex
+
+// Expands a file, directory or glob pattern into the statement files it
+// names. Directories are not searched recursively.
+func expandStatementPaths(pattern string) ([]string, error) {
+	pattern = expandHome(pattern)
+
+	info, err := os.Stat(pattern)
+	if err == nil && !info.IsDir() {
+		return []string{pattern}, nil
+	}
+	if err != nil && !isGlobPattern(pattern) {
+		return nil, err
+	}
+
+	var candidates []string
+	if err == nil && info.IsDir() {
+		dir_entries, err := os.ReadDir(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir_entry := range dir_entries {
+			candidates = append(candidates, filepath.Join(pattern, dir_entry.Name()))
+		}
+	} else {
+		candidates, err = filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := []string{}
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil || info.IsDir() || !isSupportedStatementFile(candidate) {
+			continue
+		}
+		files = append(files, candidate)
+	}
+	sort.Strings(files)
+
+	if len(files) == 0 {
+		return nil, errors.New("no statement files found in " + pattern)
+	}
+	return files, nil
+}
+
+// Replaces a leading ~ with the home directory, as the shell would
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + path[1:]
+}
+
+func isGlobPattern(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+func fileChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Imports files using at most max_import_workers goroutines. Files whose
+// contents were imported before are skipped unless force is set. With
+// dry_run nothing is written to the database. Results are in the same
+// order as files.
+func importStatementFiles(files []string, profile importProfile, dry_run bool, force bool) []fileImportResult {
+	results := stageStatementFiles(files, profile, force)
+	if dry_run {
+		for idx := range results {
+			for _, entry := range results[idx].Expenses {
+				if entry.Valid {
+					results[idx].Inserted++
+				}
+			}
+			results[idx].Rejected = len(results[idx].Expenses) - results[idx].Inserted
+		}
+		return results
+	}
+	return commitStagedImports(results)
+}
+
+// Reads and parses files in parallel without writing to the database.
+// Files imported before are marked as skipped, unless force is set, and
+// transactions already in the database are marked invalid. The same
+// contents under two names are only imported once, from the first of
+// files that parsed.
+func stageStatementFiles(files []string, profile importProfile, force bool) []fileImportResult {
+	results := make([]fileImportResult, len(files))
+	contents := make([][]byte, len(files))
+
+	for idx, filename := range files {
+		results[idx] = fileImportResult{Filename: filename, Expenses: []Expense{}}
+
+		data, err := readStatementFile(filename)
+		if err != nil {
+			results[idx].Error = err.Error()
+			continue
+		}
+		contents[idx] = data
+		results[idx].Checksum = fileChecksum(data)
+	}
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, max_import_workers)
+
+	for idx := range files {
+		if results[idx].Error != "" {
+			continue
+		}
+
+		wg.Add(1)
+		workers <- struct{}{}
+
+		go func(result *fileImportResult, data []byte) {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			imported, err := mongoIsFileImported(result.Checksum)
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			if imported && !force {
+				result.Skipped = true
+				return
+			}
+
+			entries, err := parseStatement(result.Filename, data, profile)
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			result.Duplicates, err = mongoMarkDuplicateEntries(entries)
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			result.Expenses = entries
+		}(&results[idx], contents[idx])
+	}
+
+	wg.Wait()
+	return skipRepeatedFiles(results)
+}
+
+// Skips files with the same contents as an earlier file in results that
+// was read and parsed, whichever goroutine finished first. A copy whose
+// first occurrence failed, e.g. under an extension of another format, is
+// still imported.
+func skipRepeatedFiles(results []fileImportResult) []fileImportResult {
+	staged := map[string]bool{}
+	for idx := range results {
+		result := &results[idx]
+		if result.Skipped || result.Error != "" {
+			continue
+		}
+		if staged[result.Checksum] {
+			result.Skipped = true
+			result.Duplicates = 0
+			result.Expenses = []Expense{}
+			continue
+		}
+		staged[result.Checksum] = true
+	}
+	return results
+}
+
+// Inserts the valid expenses of staged files and records the checksums of
+// files that inserted something. Files that were skipped or failed to
+// parse are left alone, and so are files whose rows were all rejected or
+// excluded, so they can be imported again with another profile.
+func commitStagedImports(results []fileImportResult) []fileImportResult {
+	import_insert_lock.Lock()
+	defer import_insert_lock.Unlock()
+
+	for idx := range results {
+		result := &results[idx]
+		if result.Skipped || result.Error != "" {
+			continue
+		}
+
+		inserted, err := mongoInsertValidEntries(result.Expenses)
+		result.Inserted = inserted
+		result.Rejected = len(result.Expenses) - inserted
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+
+		if inserted == 0 {
+			continue
+		}
+		if err := mongoRecordImportedFile(result.Checksum, result.Filename, inserted); err != nil {
+			result.Error = err.Error()
+		}
+	}
+
+	return results
+}
+
+// Whether every row of the file was already in the database, as happens
+// with overlapping statement downloads
+func (r fileImportResult) allDuplicates() bool {
+	return r.Inserted == 0 && r.Duplicates > 0 && r.Rejected == r.Duplicates
+}
+
+// All expenses from all files, for the post insert screen
+func importedExpenses(results []fileImportResult) []Expense {
+	expenses := []Expense{}
+	for _, result := range results {
+		expenses = append(expenses, result.Expenses...)
+	}
+	return expenses
+}
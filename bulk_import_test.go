@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestSkipRepeatedFiles(t *testing.T) {
+	parsed := []Expense{{Description: "TIM HORTONS", Debit: 10, Valid: true}}
+	results := skipRepeatedFiles([]fileImportResult{
+		{Filename: "a.csv", Checksum: "1", Error: "reading csv data: bad quote"},
+		{Filename: "a.ofx", Checksum: "1", Expenses: parsed},
+		{Filename: "b.ofx", Checksum: "1", Expenses: parsed},
+		{Filename: "c.ofx", Checksum: "2", Skipped: true},
+		{Filename: "d.ofx", Checksum: "2", Skipped: true},
+		{Filename: "e.ofx", Checksum: "3", Expenses: parsed},
+	})
+
+	want := []bool{false, false, true, true, true, false}
+	for idx, skipped := range want {
+		if results[idx].Skipped != skipped {
+			t.Errorf("%s: skipped = %v, want %v", results[idx].Filename, results[idx].Skipped, skipped)
+		}
+	}
+	if len(results[2].Expenses) != 0 {
+		t.Errorf("b.ofx kept %d expenses of its repeat", len(results[2].Expenses))
+	}
+	if len(results[1].Expenses) != 1 {
+		t.Errorf("a.ofx has %d expenses, want 1", len(results[1].Expenses))
+	}
+}
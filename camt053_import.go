@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Only the parts of an ISO 20022 camt.053 bank statement that budgie uses.
+// Namespaces are ignored so any camt.053.001.xx version can be read.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	IBAN    string      `xml:"Acct>Id>IBAN"`
+	Other   string      `xml:"Acct>Id>Othr>Id"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtEntry struct {
+	Reference      string            `xml:"NtryRef"`
+	ServicerRef    string            `xml:"AcctSvcrRef"`
+	Amount         string            `xml:"Amt"`
+	CreditDebit    string            `xml:"CdtDbtInd"`
+	Status         camtStatus        `xml:"Sts"`
+	BookingDate    string            `xml:"BookgDt>Dt"`
+	BookingTime    string            `xml:"BookgDt>DtTm"`
+	ValueDate      string            `xml:"ValDt>Dt"`
+	AdditionalInfo string            `xml:"AddtlNtryInf"`
+	Details        []camtTransaction `xml:"NtryDtls>TxDtls"`
+}
+
+// Older versions hold the status as text, newer ones in a Cd element
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtTransaction struct {
+	ServicerRef string   `xml:"Refs>AcctSvcrRef"`
+	Creditor    string   `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPty string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+	Debtor      string   `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPty   string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	Remittance  []string `xml:"RmtInf>Ustrd"`
+}
+
+func parseCamt053Entries(data []byte) ([]Expense, error) {
+	doc := camtDocument{}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Statements) == 0 {
+		return nil, errors.New("not a camt.053 statement, missing BkToCstmrStmt")
+	}
+
+	entries := []Expense{}
+	for _, statement := range doc.Statements {
+		account := statement.IBAN
+		if account == "" {
+			account = statement.Other
+		}
+
+		for _, ntry := range statement.Entries {
+			// pending entries can still change, only import booked ones
+			status := firstNonEmpty(ntry.Status.Code, ntry.Status.Text)
+			if status != "" && status != "BOOK" {
+				continue
+			}
+
+			entry := Expense{Account: account}
+
+			date := firstNonEmpty(ntry.BookingDate, ntry.ValueDate)
+			if date == "" && len(ntry.BookingTime) >= len(date_layout) {
+				date = ntry.BookingTime[:len(date_layout)]
+			}
+			parsedDate, err := time.Parse(date_layout, date)
+			if err == nil {
+				entry.Month = int(parsedDate.Month())
+				entry.Day = parsedDate.Day()
+				entry.Year = parsedDate.Year()
+			}
+
+			val, err := strconv.ParseFloat(strings.TrimSpace(ntry.Amount), 64)
+			if err == nil {
+				if ntry.CreditDebit == "DBIT" {
+					entry.Debit = val
+				} else {
+					entry.Credit = val
+				}
+			}
+
+			entry.FITID = ntry.ServicerRef
+			description := ""
+			if len(ntry.Details) > 0 {
+				details := ntry.Details[0]
+				if ntry.CreditDebit == "DBIT" {
+					description = firstNonEmpty(details.Creditor, details.CreditorPty)
+				} else {
+					description = firstNonEmpty(details.Debtor, details.DebtorPty)
+				}
+				if description == "" {
+					description = strings.Join(details.Remittance, " ")
+				}
+				entry.FITID = firstNonEmpty(entry.FITID, details.ServicerRef)
+			}
+			entry.Description = strings.TrimSpace(firstNonEmpty(description, ntry.AdditionalInfo))
+			entry.FITID = firstNonEmpty(entry.FITID, ntry.Reference)
+
+			checkValidEntryValues(&entry)
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestParseCamt053Fixture(t *testing.T) {
+	entries, err := parseCamt053Entries(readFixture(t, "jul_camt053.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iban := "DE89370400440532013000"
+	// the pending (PDNG) TIM HORTONS entry is left out
+	checkEntries(t, entries, []Expense{
+		{Year: 2024, Month: 7, Day: 2, Description: "GOOGLE *Audible", Debit: 200.50, Account: iban, FITID: "20240702-0001", Valid: true},
+		{Year: 2024, Month: 7, Day: 2, Description: "REAL CDN SUPERSTORE #1", Debit: 800, Account: iban, FITID: "20240702-0002", Valid: true},
+		{Year: 2024, Month: 8, Day: 10, Description: "PIKE PLACE STARBUCKS", Credit: 10, Account: iban, FITID: "20240810-0001", Valid: true},
+	})
+}
+
+func TestParseCamt053Entries(t *testing.T) {
+	wrap := func(statement string) []byte {
+		return []byte(`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08"><BkToCstmrStmt>` +
+			statement + `</BkToCstmrStmt></Document>`)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want []Expense
+	}{
+		{
+			name: "status code, other account id and booking time",
+			data: wrap(`<Stmt><Acct><Id><Othr><Id>12345</Id></Othr></Id></Acct>
+				<Ntry><NtryRef>REF1</NtryRef><Amt Ccy="CHF">42.10</Amt><CdtDbtInd>DBIT</CdtDbtInd>
+				<Sts><Cd>BOOK</Cd></Sts><BookgDt><DtTm>2024-03-05T10:00:00</DtTm></BookgDt>
+				<NtryDtls><TxDtls><RltdPties><Cdtr><Pty><Nm>MIGROS</Nm></Pty></Cdtr></RltdPties></TxDtls></NtryDtls></Ntry></Stmt>`),
+			want: []Expense{{Year: 2024, Month: 3, Day: 5, Description: "MIGROS", Debit: 42.10, Account: "12345", FITID: "REF1", Valid: true}},
+		},
+		{
+			name: "remittance when there is no counterparty",
+			data: wrap(`<Stmt><Acct><Id><IBAN>CH00</IBAN></Id></Acct>
+				<Ntry><Amt>5</Amt><CdtDbtInd>CRDT</CdtDbtInd><ValDt><Dt>2024-03-06</Dt></ValDt>
+				<NtryDtls><TxDtls><Refs><AcctSvcrRef>TX9</AcctSvcrRef></Refs><RmtInf><Ustrd>Invoice</Ustrd><Ustrd>42</Ustrd></RmtInf></TxDtls></NtryDtls></Ntry></Stmt>`),
+			want: []Expense{{Year: 2024, Month: 3, Day: 6, Description: "Invoice 42", Credit: 5, Account: "CH00", FITID: "TX9", Valid: true}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := parseCamt053Entries(test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEntries(t, entries, test.want)
+		})
+	}
+
+	if _, err := parseCamt053Entries([]byte(`<Document></Document>`)); err == nil {
+		t.Error("expected an error for a document without statements")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+const ChartLabelWidth = 16
+const ChartBarWidth = 40
+
+var sparkline_levels = []rune("▁▂▃▄▅▆▇█")
+
+// Eighths of a block, used so bars can end part way through a cell
+var bar_partial_blocks = []rune(" ▏▎▍▌▋▊▉")
+
+// Draws a bar of length value/max_value * width cells
+func renderBar(value float64, max_value float64, width int) string {
+	if max_value <= 0 || value <= 0 {
+		return ""
+	}
+
+	eighths := int(math.Round(value / max_value * float64(width*8)))
+	bar := strings.Repeat("█", eighths/8)
+	if eighths%8 > 0 {
+		bar += string(bar_partial_blocks[eighths%8])
+	}
+
+	return bar
+}
+
+// Horizontal bar chart with one labelled row per value
+func renderBarChart(labels []string, values []float64, width int) string {
+	max_value := 0.0
+	for _, value := range values {
+		max_value = math.Max(max_value, value)
+	}
+
+	s := ""
+	for i, label := range labels {
+		s += textStyle.Width(ChartLabelWidth).Render(label) + " "
+		s += chartStyle.Width(width + 1).Render(renderBar(values[i], max_value, width))
+		s += inactiveStyle.PaddingLeft(1).Width(DefaultWidth).Render(formatAmount(values[i]))
+		s += "\n"
+	}
+
+	return s
+}
+
+// One character per value, scaled between the smallest and largest value
+func renderSparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	min_value, max_value := values[0], values[0]
+	for _, value := range values {
+		min_value = math.Min(min_value, value)
+		max_value = math.Max(max_value, value)
+	}
+
+	line := []rune{}
+	for _, value := range values {
+		level := 0
+		if max_value > min_value {
+			level = int(math.Round((value - min_value) / (max_value - min_value) * float64(len(sparkline_levels)-1)))
+		}
+		line = append(line, sparkline_levels[level])
+	}
+
+	return chartStyle.Render(string(line))
+}
@@ -0,0 +1,472 @@
+// Non-interactive subcommands for scripting the monthly workflow.
+// Each subcommand shares parsing and storage code with the TUI.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Exit codes
+const (
+	exit_ok        = 0
+	exit_error     = 1 // storage or file errors
+	exit_usage     = 2 // bad arguments
+	exit_not_found = 3 // nothing matched, nothing imported or an ID does not exist
+)
+
+type cliCommand struct {
+	usage string
+	run   func(args []string) error
+
+	// commands that do not read storage, or migrate it themselves, are
+	// run without preparing it first
+	skip_prepare bool
+}
+
+var cli_commands map[string]cliCommand
+
+func init() {
+	// assigned in init because help refers back to the command table
+	cli_commands = map[string]cliCommand{
+		"serve":  {usage: "serve [--addr host:port]", run: runServe},
+		"import": {usage: "import <file|dir|glob>... [--profile name] [--dry-run] [--force]", run: runImport},
+		"find": {
+			usage: "find [search flags] [--format jsonl|csv|...]",
+			run:   runFind,
+		},
+		"delete": {usage: "delete --id <id> [--id <id> ...]", run: runDelete},
+		"report": {usage: "report [--month YYYY-MM] [--compare-with YYYY-MM] [--json]", run: runReport},
+		"export": {
+			usage: "export --format csv|jsonl|ofx|ledger|hledger|beancount [--out file] [--from --to] [search flags]",
+			run:   runExport,
+		},
+		"recurring": {
+			usage: "recurring add --desc --debit|--credit --schedule \"1st of every month\" [--category --account --start] | list | delete <id>",
+			run:   runRecurring,
+		},
+		"run-recurring": {usage: "run-recurring", run: runRecurringEntries},
+		"subscriptions": {usage: "subscriptions [--alerts] [--json]", run: runSubscriptions},
+		"watch": {
+			usage: "watch <dir> [--interval 10s] [--archive dir] [--log file] [--once]",
+			run:   runWatch,
+		},
+		"forecast": {usage: "forecast [--json]", run: runForecast},
+		"migrate":  {usage: "migrate", run: runMigrate, skip_prepare: true},
+		"searches": {usage: "searches save <name> [search flags] | list | delete <name>", run: runSearches},
+		"attach":   {usage: "attach <id> <file>...", run: runAttach},
+		"detach":   {usage: "detach <id> <name|hash>", run: runDetach},
+		"note":     {usage: "note <id> [text]", run: runNote},
+		"receipts": {
+			usage: "receipts [dir] [--window days] [--threshold amount] [--from --to] [--attach]",
+			run:   runReceipts,
+		},
+		"help": {usage: "help", run: runHelp, skip_prepare: true},
+	}
+}
+
+// Wraps errors caused by bad arguments so they exit with exit_usage
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+// Returned when a command ran but found nothing to act on
+var errNothingFound = errors.New("nothing found")
+
+func runCLI(args []string) int {
+	command, ok := cli_commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", args[0])
+		printUsage()
+		return exit_usage
+	}
+
+	var err error
+	if !command.skip_prepare && !askedForHelp(args[1:]) {
+		_, err = prepareStorage()
+	}
+	if err == nil {
+		err = command.run(args[1:])
+	}
+
+	var usage_err usageError
+	switch {
+	case err == nil:
+		return exit_ok
+	case errors.Is(err, flag.ErrHelp):
+		return exit_ok
+	case errors.As(err, &usage_err):
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		fmt.Fprintln(os.Stderr, "Usage: budgie", command.usage)
+		return exit_usage
+	case errors.Is(err, errNothingFound):
+		return exit_not_found
+	default:
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exit_error
+	}
+}
+
+// Whether the flags ask for the command's usage, which needs no storage
+func askedForHelp(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "-h", "-help", "--help", "--h":
+			return true
+		}
+	}
+	return false
+}
+
+func runHelp(args []string) error {
+	printUsage()
+	return nil
+}
+
+func printUsage() {
+	names := []string{}
+	for name := range cli_commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: budgie [command]")
+	fmt.Fprintln(os.Stderr, "Without a command the TUI is started.")
+	fmt.Fprintln(os.Stderr)
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  budgie", cli_commands[name].usage)
+	}
+}
+
+// Parses flags that may appear before or after positional arguments
+// and returns the positional arguments
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usageError{err}
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func writeJSONOutput(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// Adds the search flags shared by find and export.
+// Returns a function building the search once flags are parsed.
+func addSearchFlags(flags *flag.FlagSet) func() (entrySearch, error) {
+	search_params := addSearchParamFlags(flags)
+
+	return func() (entrySearch, error) {
+		params, err := search_params()
+		if err != nil {
+			return entrySearch{}, err
+		}
+		search, err := parseSearch(func(key string) string { return params[key] })
+		if err != nil {
+			return search, usageError{err}
+		}
+		return search, nil
+	}
+}
+
+// Returns a function collecting the parseSearch parameters of the search
+// flags once they are parsed, starting from the --saved search if given
+func addSearchParamFlags(flags *flag.FlagSet) func() (map[string]string, error) {
+	params := map[string]*string{
+		"from":        flags.String("from", "", "first date, YYYY-MM-DD (or YYYY-MM, YYYY)"),
+		"to":          flags.String("to", "", "last date, YYYY-MM-DD (or YYYY-MM, YYYY)"),
+		"year":        flags.String("year", "", "year to match"),
+		"month":       flags.String("month", "", "month to match, e.g. Jan or 1"),
+		"day":         flags.String("day", "", "day to match"),
+		"description": flags.String("desc", "", "text the description contains"),
+		"desc_regex":  flags.String("desc-regex", "", "regular expression the description matches"),
+		"debit":       flags.String("debit", "", "debit amount to match"),
+		"credit":      flags.String("credit", "", "credit amount to match"),
+		"min_debit":   flags.String("min-debit", "", "smallest debit amount"),
+		"max_debit":   flags.String("max-debit", "", "largest debit amount"),
+		"min_credit":  flags.String("min-credit", "", "smallest credit amount"),
+		"max_credit":  flags.String("max-credit", "", "largest credit amount"),
+		"min_amount":  flags.String("min-amount", "", "smallest debit or credit amount"),
+		"max_amount":  flags.String("max-amount", "", "largest debit or credit amount"),
+		"q":           flags.String("query", "", `search query, e.g. "desc:tim debit>50 -tag:reimbursed"`),
+		"sort":        flags.String("sort", "", "date, description, debit, credit, category or relevance, prefix with - to reverse"),
+	}
+	saved_name := flags.String("saved", "", "name of a saved search, other search flags are added to it")
+
+	return func() (map[string]string, error) {
+		merged := map[string]string{}
+		if *saved_name != "" {
+			saved, err := mongoFindSavedSearch(*saved_name)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, usageError{fmt.Errorf("no saved search named %q", *saved_name)}
+			} else if err != nil {
+				return nil, err
+			}
+			for key, value := range saved.Params {
+				merged[key] = value
+			}
+		}
+		for key, value := range params {
+			if *value != "" {
+				merged[key] = *value
+			}
+		}
+		return merged, nil
+	}
+}
+
+// `budgie import <file|dir|glob>... [--profile name]`
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	profile_name := flags.String("profile", "", "csv layout from the config file")
+	dry_run := flags.Bool("dry-run", false, "parse and validate without writing to the database")
+	force := flags.Bool("force", false, "import files even if they were imported before")
+	patterns, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(patterns) == 0 {
+		return usageError{errors.New("expected a file, directory or glob pattern")}
+	}
+
+	profile, err := findImportProfile(*profile_name)
+	if err != nil {
+		return usageError{err}
+	}
+
+	files := []string{}
+	for _, pattern := range patterns {
+		expanded, err := expandStatementPaths(pattern)
+		if err != nil {
+			return err
+		}
+		files = append(files, expanded...)
+	}
+
+	results := importStatementFiles(files, profile, *dry_run, *force)
+	if err := writeJSONOutput(results); err != nil {
+		return err
+	}
+
+	inserted := 0
+	failed := 0
+	for _, result := range results {
+		inserted += result.Inserted
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed to import", failed, len(results))
+	}
+	if inserted == 0 {
+		return errNothingFound
+	}
+	return nil
+}
+
+// `budgie find` prints matching expenses, one JSON object per line by default
+func runFind(args []string) error {
+	flags := flag.NewFlagSet("find", flag.ContinueOnError)
+	format_name := flags.String("format", "jsonl", "output format, same as export")
+	build_search := addSearchFlags(flags)
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	format, err := findExportFormat(*format_name)
+	if err != nil {
+		return usageError{err}
+	}
+	search, err := build_search()
+	if err != nil {
+		return err
+	}
+
+	expenses, err := mongoFindEntries(search)
+	if err != nil {
+		return err
+	}
+
+	if err := format.write(os.Stdout, expenses); err != nil {
+		return err
+	}
+	if len(expenses) == 0 {
+		return errNothingFound
+	}
+	return nil
+}
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+type deleteResult struct {
+	Deleted  []string `json:"deleted"`
+	NotFound []string `json:"not_found"`
+}
+
+// `budgie delete --id <id>`
+func runDelete(args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ContinueOnError)
+	ids := stringList{}
+	flags.Var(&ids, "id", "ID of the expense to delete, can be repeated")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return usageError{errors.New("at least one --id is required")}
+	}
+
+	object_ids := []primitive.ObjectID{}
+	for _, id := range ids {
+		object_id, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return usageError{fmt.Errorf("invalid expense id %q", id)}
+		}
+		object_ids = append(object_ids, object_id)
+	}
+
+	result := deleteResult{Deleted: []string{}, NotFound: []string{}}
+	for _, id := range object_ids {
+		found, err := mongoDeleteEntryByID(id)
+		if err != nil {
+			return err
+		}
+		if found {
+			result.Deleted = append(result.Deleted, id.Hex())
+		} else {
+			result.NotFound = append(result.NotFound, id.Hex())
+		}
+	}
+
+	if err := writeJSONOutput(result); err != nil {
+		return err
+	}
+	if len(result.NotFound) > 0 {
+		return errNothingFound
+	}
+	return nil
+}
+
+type comparisonRow struct {
+	Category      string   `json:"category"`
+	A             float64  `json:"a"`
+	B             float64  `json:"b"`
+	Delta         float64  `json:"delta"`
+	PercentChange *float64 `json:"percent_change"`
+}
+
+type comparisonOutput struct {
+	A     string          `json:"a"`
+	B     string          `json:"b"`
+	Rows  []comparisonRow `json:"rows"`
+	Total comparisonRow   `json:"total"`
+}
+
+func newComparisonRow(c categoryComparison) comparisonRow {
+	row := comparisonRow{Category: c.Category, A: c.A, B: c.B, Delta: c.Delta()}
+	if change, ok := c.PercentChange(); ok {
+		row.PercentChange = &change
+	}
+	return row
+}
+
+// `budgie report --month YYYY-MM` prints the monthly summary, or with
+// --compare-with the per category comparison of two months
+func runReport(args []string) error {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	month_flag := flags.String("month", time.Now().Format("2006-01"), "month to report on, YYYY-MM")
+	compare_with := flags.String("compare-with", "", "month to compare against, YYYY-MM")
+	as_json := flags.Bool("json", false, "print JSON instead of text")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	month, err := time.Parse("2006-01", *month_flag)
+	if err != nil {
+		return usageError{errors.New("invalid month, format: YYYY-MM")}
+	}
+
+	if *compare_with != "" {
+		other, err := time.Parse("2006-01", *compare_with)
+		if err != nil {
+			return usageError{errors.New("invalid compare-with month, format: YYYY-MM")}
+		}
+		report, err := buildComparisonReport(
+			monthPeriod(other.Year(), int(other.Month())),
+			monthPeriod(month.Year(), int(month.Month())))
+		if err != nil {
+			return err
+		}
+
+		if !*as_json {
+			return writeComparisonCSV(os.Stdout, report)
+		}
+		output := comparisonOutput{A: report.a.String(), B: report.b.String(), Total: newComparisonRow(report.total)}
+		for _, c := range report.rows {
+			output.Rows = append(output.Rows, newComparisonRow(c))
+		}
+		return writeJSONOutput(output)
+	}
+
+	summary, err := mongoMonthlySummary(month.Year(), int(month.Month()))
+	if err != nil {
+		return err
+	}
+
+	if *as_json {
+		return writeJSONOutput(summary)
+	}
+
+	fmt.Printf("%s %d\n", time.Month(summary.Month), summary.Year)
+	fmt.Printf("Entries:       %d\n", summary.Count)
+	fmt.Printf("Total debits:  %s\n", formatAmount(summary.TotalDebit))
+	fmt.Printf("Total credits: %s\n", formatAmount(summary.TotalCredit))
+	fmt.Printf("Net:           %s\n", formatAmount(summary.Net()))
+	fmt.Println("\nTop payees:")
+	for _, payee := range summary.TopPayees {
+		fmt.Printf("  %-36s %12s  (%d)\n", payee.Description, formatAmount(payee.Total), payee.Count)
+	}
+	fmt.Println("\nSpending per day:")
+	for _, day := range summary.DailyDebits {
+		if day.Debit != 0 {
+			fmt.Printf("  %2d %12s\n", day.Day, formatAmount(day.Debit))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const date_layout = "2006-01-02"
+
+// An inclusive range of days
+type period struct {
+	from time.Time
+	to   time.Time
+}
+
+func (p period) String() string {
+	return p.from.Format(date_layout) + ".." + p.to.Format(date_layout)
+}
+
+func newPeriod(from string, to string) (period, error) {
+	from_date, err := time.Parse(date_layout, from)
+	if err != nil {
+		return period{}, errors.New("invalid from date, format: YYYY-MM-DD")
+	}
+	to_date, err := time.Parse(date_layout, to)
+	if err != nil {
+		return period{}, errors.New("invalid to date, format: YYYY-MM-DD")
+	}
+	if to_date.Before(from_date) {
+		return period{}, errors.New("to date is before from date")
+	}
+	return period{from: from_date, to: to_date}, nil
+}
+
+func dateOf(year int, month int, day int) time.Time {
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+func monthPeriod(year int, month int) period {
+	from := dateOf(year, month, 1)
+	return period{from: from, to: from.AddDate(0, 1, -1)}
+}
+
+func quarterPeriod(year int, quarter int) period {
+	from := dateOf(year, (quarter-1)*3+1, 1)
+	return period{from: from, to: from.AddDate(0, 3, -1)}
+}
+
+// Sorts dates the way they are stored, e.g. 20240702
+func dateKey(t time.Time) int {
+	return t.Year()*10000 + int(t.Month())*100 + t.Day()
+}
+
+type comparisonPreset struct {
+	name    string
+	periods func(today time.Time) (period, period)
+}
+
+var comparison_presets = []comparisonPreset{
+	{
+		name: "This month vs same month last year",
+		periods: func(today time.Time) (period, period) {
+			return monthPeriod(today.Year()-1, int(today.Month())), monthPeriod(today.Year(), int(today.Month()))
+		},
+	},
+	{
+		name: "Last month vs this month",
+		periods: func(today time.Time) (period, period) {
+			year, month := addMonths(today.Year(), int(today.Month()), -1)
+			return monthPeriod(year, month), monthPeriod(today.Year(), int(today.Month()))
+		},
+	},
+	{
+		name: "Last quarter vs this quarter",
+		periods: func(today time.Time) (period, period) {
+			quarter := (int(today.Month())-1)/3 + 1
+			if quarter == 1 {
+				return quarterPeriod(today.Year()-1, 4), quarterPeriod(today.Year(), quarter)
+			}
+			return quarterPeriod(today.Year(), quarter-1), quarterPeriod(today.Year(), quarter)
+		},
+	},
+	{
+		name: "Prior year to date vs year to date",
+		periods: func(today time.Time) (period, period) {
+			this_ytd := period{from: dateOf(today.Year(), 1, 1), to: dateOf(today.Year(), int(today.Month()), today.Day())}
+			last_ytd := period{from: this_ytd.from.AddDate(-1, 0, 0), to: this_ytd.to.AddDate(-1, 0, 0)}
+			return last_ytd, this_ytd
+		},
+	},
+}
+
+type categoryComparison struct {
+	Category string
+	A        float64
+	B        float64
+}
+
+func (c categoryComparison) Delta() float64 {
+	return c.B - c.A
+}
+
+// Percentage change from A to B. ok is false when A is zero.
+func (c categoryComparison) PercentChange() (float64, bool) {
+	if c.A == 0 {
+		return 0, false
+	}
+	return (c.B - c.A) / c.A * 100, true
+}
+
+type comparisonReport struct {
+	a     period
+	b     period
+	rows  []categoryComparison
+	total categoryComparison
+}
+
+func buildComparisonReport(a period, b period) (comparisonReport, error) {
+	report := comparisonReport{a: a, b: b}
+
+	a_totals, err := mongoCategoryTotalsBetween(a.from, a.to)
+	if err != nil {
+		return report, err
+	}
+	b_totals, err := mongoCategoryTotalsBetween(b.from, b.to)
+	if err != nil {
+		return report, err
+	}
+
+	report.rows, report.total = compareCategoryTotals(a_totals, b_totals)
+	return report, nil
+}
+
+// Lines up spending per category of two periods, biggest spending in b first
+func compareCategoryTotals(a_totals []categoryTotal, b_totals []categoryTotal) ([]categoryComparison, categoryComparison) {
+	by_category := map[string]*categoryComparison{}
+	rows := []*categoryComparison{}
+
+	row := func(category string) *categoryComparison {
+		if existing, ok := by_category[category]; ok {
+			return existing
+		}
+		created := &categoryComparison{Category: category}
+		by_category[category] = created
+		rows = append(rows, created)
+		return created
+	}
+
+	total := categoryComparison{Category: "Total"}
+	for _, t := range a_totals {
+		row(t.Category).A = t.Debit
+		total.A += t.Debit
+	}
+	for _, t := range b_totals {
+		row(t.Category).B = t.Debit
+		total.B += t.Debit
+	}
+
+	comparisons := make([]categoryComparison, len(rows))
+	for i, r := range rows {
+		comparisons[i] = *r
+	}
+	sort.SliceStable(comparisons, func(i, j int) bool {
+		if comparisons[i].B != comparisons[j].B {
+			return comparisons[i].B > comparisons[j].B
+		}
+		return comparisons[i].A > comparisons[j].A
+	})
+
+	return comparisons, total
+}
+
+func formatPercentChange(c categoryComparison) string {
+	change, ok := c.PercentChange()
+	if !ok {
+		return "n/a"
+	}
+	return strconv.FormatFloat(change, 'f', 1, 64) + "%"
+}
+
+func writeComparisonCSV(w io.Writer, report comparisonReport) error {
+	writer := csv.NewWriter(w)
+
+	records := [][]string{{"Category", report.a.String(), report.b.String(), "Delta", "Change"}}
+	for _, c := range append(report.rows, report.total) {
+		records = append(records, []string{
+			c.Category,
+			formatAmount(c.A),
+			formatAmount(c.B),
+			formatAmount(c.Delta()),
+			formatPercentChange(c),
+		})
+	}
+
+	return writer.WriteAll(records)
+}
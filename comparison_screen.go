@@ -0,0 +1,258 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	comparison_a_from     = iota
+	comparison_a_to       = iota
+	comparison_b_from     = iota
+	comparison_b_to       = iota
+	num_comparison_fields = iota
+)
+
+const ComparisonColumnWidth = 26
+
+type comparisonScreenModel struct {
+	fields     [num_comparison_fields]string
+	validated  [num_comparison_fields]bool
+	cursor     int
+	preset_idx int
+	report     *comparisonReport
+	feedback   string
+	failed     bool
+}
+
+func createComparisonScreenModel() comparisonScreenModel {
+	m := comparisonScreenModel{preset_idx: -1}
+	return applyComparisonPreset(m, 0)
+}
+
+// Fills in the periods of a preset and runs the comparison
+func applyComparisonPreset(m comparisonScreenModel, idx int) comparisonScreenModel {
+	m.preset_idx = idx
+	a, b := comparison_presets[idx].periods(time.Now())
+
+	m.fields[comparison_a_from] = a.from.Format(date_layout)
+	m.fields[comparison_a_to] = a.to.Format(date_layout)
+	m.fields[comparison_b_from] = b.from.Format(date_layout)
+	m.fields[comparison_b_to] = b.to.Format(date_layout)
+	for i := range m.validated {
+		m.validated[i] = true
+	}
+
+	return runComparison(m)
+}
+
+func runComparison(m comparisonScreenModel) comparisonScreenModel {
+	a, err := newPeriod(m.fields[comparison_a_from], m.fields[comparison_a_to])
+	if err != nil {
+		m.feedback = "Period A: " + err.Error()
+		m.failed = true
+		return m
+	}
+	b, err := newPeriod(m.fields[comparison_b_from], m.fields[comparison_b_to])
+	if err != nil {
+		m.feedback = "Period B: " + err.Error()
+		m.failed = true
+		return m
+	}
+
+	report, err := buildComparisonReport(a, b)
+	if err != nil {
+		m.feedback = "Error loading comparison: " + err.Error()
+		m.failed = true
+		return m
+	}
+
+	m.report = &report
+	m.feedback = default_feedback
+	m.failed = false
+	return m
+}
+
+func (m comparisonScreenModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m comparisonScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+
+		switch msg.String() {
+
+		case "up":
+			if m.cursor > comparison_a_from {
+				m.cursor--
+			}
+
+		case "down":
+			if m.cursor < comparison_b_to {
+				m.cursor++
+			}
+
+		case "tab":
+			m = applyComparisonPreset(m, (m.preset_idx+1)%len(comparison_presets))
+
+		case "backspace":
+			m.fields[m.cursor] = removeLastChar(m.fields[m.cursor])
+			m.validated[m.cursor] = false
+			m.preset_idx = -1
+
+		case "enter":
+			if _, err := time.Parse(date_layout, m.fields[m.cursor]); err != nil {
+				m.validated[m.cursor] = false
+				m.feedback = "Invalid date! Format: YYYY-MM-DD"
+				m.failed = true
+				break
+			}
+			m.validated[m.cursor] = true
+			if m.cursor < comparison_b_to {
+				m.cursor++
+			}
+			if allComparisonFieldsValid(m) {
+				m = runComparison(m)
+			}
+
+		case "ctrl+e":
+			m = exportComparison(m)
+
+		case "ctrl+c":
+			return createHomeScreenModel(), nil
+
+		default:
+			if len(m.fields[m.cursor]) < len(date_layout) {
+				m.fields[m.cursor] += msg.String()
+				m.validated[m.cursor] = false
+				m.preset_idx = -1
+			}
+		}
+	}
+
+	return m, nil
+}
+
+func allComparisonFieldsValid(m comparisonScreenModel) bool {
+	for _, valid := range m.validated {
+		if !valid {
+			return false
+		}
+	}
+	return true
+}
+
+// Writes the comparison shown on screen to a csv file in the current directory
+func exportComparison(m comparisonScreenModel) comparisonScreenModel {
+	if m.report == nil {
+		m.feedback = "Nothing to export yet."
+		m.failed = true
+		return m
+	}
+
+	filename := "comparison_" + strings.ReplaceAll(m.report.a.String()+"_vs_"+m.report.b.String(), "..", "_") + ".csv"
+	f, err := os.Create(filename)
+	if err != nil {
+		m.feedback = "Error exporting comparison: " + err.Error()
+		m.failed = true
+		return m
+	}
+	defer f.Close()
+
+	if err := writeComparisonCSV(f, *m.report); err != nil {
+		m.feedback = "Error exporting comparison: " + err.Error()
+		m.failed = true
+		return m
+	}
+
+	m.feedback = "Exported to " + filename
+	m.failed = false
+	return m
+}
+
+func (m comparisonScreenModel) View() string {
+	s := selectedStyle.Width(HomeScreenWidth).Render("> Compare periods") + "\n"
+
+	preset := "Custom"
+	if m.preset_idx >= 0 {
+		preset = comparison_presets[m.preset_idx].name
+	}
+	s += textStyle.PaddingLeft(2).PaddingRight(2).Render("Preset: "+preset) + "\n"
+
+	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Period A from: ") +
+		selectComparisonFieldStyle(m, comparison_a_from).Width(FindEntryLabelWidth).Render(m.fields[comparison_a_from]) + "\n"
+	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Period A to: ") +
+		selectComparisonFieldStyle(m, comparison_a_to).Width(FindEntryLabelWidth).Render(m.fields[comparison_a_to]) + "\n"
+	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Period B from: ") +
+		selectComparisonFieldStyle(m, comparison_b_from).Width(FindEntryLabelWidth).Render(m.fields[comparison_b_from]) + "\n"
+	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Period B to: ") +
+		selectComparisonFieldStyle(m, comparison_b_to).Width(FindEntryLabelWidth).Render(m.fields[comparison_b_to]) + "\n\n"
+
+	if m.report != nil {
+		s = renderComparisonTable(*m.report, s)
+	}
+
+	s += "\n" + textStyle.Render("Press tab to cycle presets, ctrl+e to export to csv.") + "\n"
+	if m.failed {
+		s += errorStyle.Render(m.feedback) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
+	return s
+}
+
+func renderComparisonTable(report comparisonReport, s string) string {
+	s += textStyle.Width(ChartLabelWidth).Render("Category")
+	s += " | "
+	s += textStyle.Width(ComparisonColumnWidth).Render("A: " + report.a.String())
+	s += " | "
+	s += textStyle.Width(ComparisonColumnWidth).Render("B: " + report.b.String())
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Delta")
+	s += " | "
+	s += textStyle.Width(DateWidth + 5).Render("Change")
+	s += "\n"
+
+	for _, c := range append(report.rows, report.total) {
+		style := inactiveStyle
+		if c.Category == report.total.Category {
+			style = textStyle
+		}
+
+		delta_style := style
+		if c.Delta() > 0 {
+			delta_style = errorStyle
+		} else if c.Delta() < 0 {
+			delta_style = selectedStyle
+		}
+
+		line := style.Width(ChartLabelWidth).Render(c.Category)
+		line += " | "
+		line += style.Width(ComparisonColumnWidth).Render(formatAmount(c.A))
+		line += " | "
+		line += style.Width(ComparisonColumnWidth).Render(formatAmount(c.B))
+		line += " | "
+		line += delta_style.Width(DefaultWidth).Render(formatAmount(c.Delta()))
+		line += " | "
+		line += delta_style.Width(DateWidth + 5).Render(formatPercentChange(c))
+		s += line + "\n"
+	}
+
+	return s
+}
+
+func selectComparisonFieldStyle(m comparisonScreenModel, index int) lipgloss.Style {
+	if m.cursor == index {
+		return selectedStyle.PaddingLeft(2).PaddingRight(2)
+	} else if !m.validated[index] {
+		return errorStyle.PaddingLeft(2).PaddingRight(2)
+	} else {
+		return inactiveStyle.PaddingLeft(2).PaddingRight(2)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Settings read from $XDG_CONFIG_HOME/budgie/config.json
+// (~/.config/budgie/config.json). Missing settings use the defaults below.
+type budgieConfig struct {
+	Ledger        ledgerConfig             `json:"ledger"`
+	Profiles      map[string]importProfile `json:"profiles"`
+	LastImportDir string                   `json:"last_import_dir"` // where the file browser opens
+
+	// where attached receipts are stored, next to this file by default
+	AttachmentsDir string `json:"attachments_dir"`
+
+	// debits of at least this amount are flagged when they have no receipt
+	ReceiptThreshold float64 `json:"receipt_threshold"`
+	// days a receipt's date may be before or after the expense it matches
+	ReceiptWindowDays int `json:"receipt_window_days"`
+
+	// balance of each account before its first entry, used by the forecast
+	OpeningBalances map[string]float64 `json:"opening_balances"`
+}
+
+// Maps budgie categories and accounts onto plain text accounting accounts
+type ledgerConfig struct {
+	Currency       string            `json:"currency"`
+	ExpenseAccount string            `json:"expense_account"` // for debits without a mapped category
+	IncomeAccount  string            `json:"income_account"`  // for credits without a mapped category
+	AssetAccount   string            `json:"asset_account"`   // for expenses without a mapped account
+	Categories     map[string]string `json:"categories"`
+	Accounts       map[string]string `json:"accounts"`
+}
+
+func defaultConfig() budgieConfig {
+	return budgieConfig{
+		Ledger: ledgerConfig{
+			Currency:       ofx_currency,
+			ExpenseAccount: "Expenses:Uncategorized",
+			IncomeAccount:  "Income:Uncategorized",
+			AssetAccount:   "Assets:Bank",
+			Categories:     map[string]string{},
+			Accounts:       map[string]string{},
+		},
+		ReceiptThreshold:  75,
+		ReceiptWindowDays: 3,
+	}
+}
+
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "budgie", "config.json"), nil
+}
+
+// Returns the defaults if there is no config file yet
+func loadConfig() (budgieConfig, error) {
+	cfg := defaultConfig()
+
+	path, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	} else if err != nil {
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, errors.New(path + ": " + err.Error())
+	}
+	return cfg, nil
+}
+
+func saveConfig(cfg budgieConfig) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Stores the directory statements were last imported from
+func rememberImportDir(dir string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	abs_dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if cfg.LastImportDir == abs_dir {
+		return nil
+	}
+	cfg.LastImportDir = abs_dir
+	return saveConfig(cfg)
+}
@@ -9,6 +9,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 const (
@@ -23,35 +24,32 @@ type action struct {
 }
 
 type deleteEntriesModel struct {
-	entry_to_search        Expense
-	active_view            int
-	feedback               string
-	found_entries          []Expense
-	entries                []expensePlaceholder
-	selected_entries       []bool
-	found_entries_page_idx int
-	entries_cursor         int
-	prompt_text            string
+	pager            entryPager
+	active_view      int
+	feedback         string
+	entries          []expensePlaceholder
+	selected_entries map[primitive.ObjectID]Expense // kept across pages
+	entries_cursor   int
+	prompt_text      string
 }
 
-func createDeleteEntriesModel(found_entries []Expense, entry_to_search Expense) deleteEntriesModel {
+func createDeleteEntriesModel(search entrySearch) deleteEntriesModel {
 	model := deleteEntriesModel{
-		entry_to_search:  entry_to_search,
-		found_entries:    found_entries,
-		selected_entries: make([]bool, len(found_entries)),
+		pager:            entryPager{search: search},
+		selected_entries: map[primitive.ObjectID]Expense{},
 		feedback:         default_feedback,
 		active_view:      delete_entries_view,
 		prompt_text:      default_feedback,
 	}
 
-	return populateDeleteEntries(model)
+	return loadDeletePage(model, 0)
 }
 
 func populateDeleteEntries(m deleteEntriesModel) deleteEntriesModel {
 
-	m.entries = make([]expensePlaceholder, len(m.found_entries))
+	m.entries = make([]expensePlaceholder, len(m.pager.entries))
 
-	for idx, entry := range m.found_entries {
+	for idx, entry := range m.pager.entries {
 		m.entries[idx].Year = strconv.Itoa(entry.Year)
 		m.entries[idx].Month = strconv.Itoa(entry.Month)
 		m.entries[idx].Day = strconv.Itoa(entry.Day)
@@ -63,6 +61,66 @@ func populateDeleteEntries(m deleteEntriesModel) deleteEntriesModel {
 	return m
 }
 
+// Fetches a page of the search; the selection is kept
+func loadDeletePage(m deleteEntriesModel, page_idx int) deleteEntriesModel {
+	pager, err := loadEntryPage(m.pager.search, page_idx)
+	if err != nil {
+		m.prompt_text = "Error searching entries: " + err.Error()
+		return m
+	}
+
+	m.pager = pager
+	m.entries_cursor = 0
+	return populateDeleteEntries(m)
+}
+
+func turnDeletePage(m deleteEntriesModel, delta int) deleteEntriesModel {
+	pager, err := m.pager.turn(delta)
+	if err != nil {
+		m.prompt_text = "Error searching entries: " + err.Error()
+		return m
+	}
+
+	if pager.page_idx != m.pager.page_idx {
+		m.entries_cursor = 0
+	}
+	m.pager = pager
+	return populateDeleteEntries(m)
+}
+
+func toggleDeleteSelection(m deleteEntriesModel) deleteEntriesModel {
+	if m.entries_cursor >= len(m.pager.entries) {
+		return m
+	}
+
+	entry := m.pager.entries[m.entries_cursor]
+	if _, selected := m.selected_entries[entry.ID]; selected {
+		delete(m.selected_entries, entry.ID)
+	} else {
+		m.selected_entries[entry.ID] = entry
+	}
+	return m
+}
+
+// ctrl+o sorts by the next column, ctrl+r reverses the order. Relevance
+// is skipped unless the search has a text: term.
+func nextEntrySort(search entrySearch, key string) entrySort {
+	sort := search.sort
+	if key == "ctrl+o" {
+		sort.column = (sort.column + 1) % num_sort_columns
+		if sort.column == sort_relevance && !hasTextTerm(search.query) {
+			sort.column = (sort.column + 1) % num_sort_columns
+		}
+	} else {
+		sort.descending = !sort.descending
+	}
+	return sort
+}
+
+func renderSortHint(sort entrySort) string {
+	return textStyle.Render("Sorted by "+sort.String()+". Press ctrl+o to sort by another column, ctrl+r to reverse.") + "\n"
+}
+
 func (m deleteEntriesModel) Init() tea.Cmd {
 	return nil
 }
@@ -82,62 +140,44 @@ func (m deleteEntriesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case "down":
 			if m.active_view == delete_entries_view {
-				num_entries_on_page := min(num_entries_per_page, len(m.found_entries)-(m.found_entries_page_idx*num_entries_per_page))
-				if m.entries_cursor < num_entries_on_page-1 {
+				if m.entries_cursor < len(m.pager.entries)-1 {
 					m.entries_cursor++
 				}
 			}
 
-		case "left":
-			if m.found_entries_page_idx > 0 {
-				m.found_entries_page_idx--
-				m.entries_cursor = 0
-			}
-		case "right":
-			num_pages := len(m.found_entries) / num_entries_per_page
-			if m.found_entries_page_idx < num_pages {
-				m.found_entries_page_idx++
-				m.entries_cursor = 0
-			}
+		case "left", "<":
+			m = turnDeletePage(m, -1)
+		case "right", ">":
+			m = turnDeletePage(m, 1)
 
 		case "tab":
 			m.active_view = (m.active_view + 1) % delete_num_views
 		case "x":
 			// does same thing as enter for entries view
 			if m.active_view == delete_entries_view {
-				if !m.selected_entries[m.found_entries_page_idx*num_entries_per_page+m.entries_cursor] {
-					m.selected_entries[m.found_entries_page_idx*num_entries_per_page+m.entries_cursor] = true
-				} else {
-					m.selected_entries[m.found_entries_page_idx*num_entries_per_page+m.entries_cursor] = false
-				}
+				m = toggleDeleteSelection(m)
 			}
 		case "enter":
 			if m.active_view == delete_entries_view {
-				if !m.selected_entries[m.found_entries_page_idx*num_entries_per_page+m.entries_cursor] {
-					m.selected_entries[m.found_entries_page_idx*num_entries_per_page+m.entries_cursor] = true
-				} else {
-					m.selected_entries[m.found_entries_page_idx*num_entries_per_page+m.entries_cursor] = false
-				}
+				m = toggleDeleteSelection(m)
 			} else { // action view
-				selected_entries := make([]Expense, 0)
-				for idx, selected := range m.selected_entries {
-					if selected {
-						selected_entries = append(selected_entries, m.found_entries[idx])
-					}
+				selected_entries := make([]Expense, 0, len(m.selected_entries))
+				for _, entry := range m.selected_entries {
+					selected_entries = append(selected_entries, entry)
 				}
 
 				mongoDeleteEntries(selected_entries)
 
-				// reset page
-				m.found_entries = mongoFindMatchingEntries(m.entry_to_search)
-				m.selected_entries = make([]bool, len(m.found_entries))
-
+				// stay on the same page unless it no longer exists
+				m.selected_entries = map[primitive.ObjectID]Expense{}
+				m = loadDeletePage(m, m.pager.page_idx)
 				m.active_view = delete_entries_view
-				m.entries_cursor = 0
-
-				m = populateDeleteEntries(m)
 			}
 
+		case "ctrl+o", "ctrl+r":
+			m.pager.search.sort = nextEntrySort(m.pager.search, msg.String())
+			m = loadDeletePage(m, 0)
+
 		case "ctrl+c":
 			return createHomeScreenModel(), nil
 		default:
@@ -164,19 +204,14 @@ func renderDeleteExpenses(m deleteEntriesModel, s string) string {
 
 	s += "\n" + textStyle.Width((DateWidth+3)*3).Render("Matching Entries")
 
-	if len(m.found_entries) > 0 {
-		page_str := "Entries: " +
-			strconv.Itoa(m.found_entries_page_idx*num_entries_per_page+1) + "-" +
-			strconv.Itoa(min((m.found_entries_page_idx+1)*num_entries_per_page, len(m.found_entries))) + " / " +
-			strconv.Itoa(len(m.found_entries))
-
-		s += textStyle.Width(DescriptionWidth + 3).Render(page_str)
+	if len(m.pager.entries) > 0 {
+		s += textStyle.Width(DescriptionWidth + 3).Render(m.pager.status())
 		s += textStyle.Width((DefaultWidth + 3) * 2).Render("Press < or > to switch pages")
 		s += activeDeleteViewStyle(m.active_view, delete_entries_view).Width(3).Render(sym)
 	}
 
 	s += "\n" + textStyle.Render("Press tab to switch between search, entry, and delete sections.")
-	s += "\n"
+	s += "\n" + renderSortHint(m.pager.search.sort)
 	s += textStyle.Width(DateWidth).Render("Year")
 	s += " | "
 	s += textStyle.Width(DateWidth).Render("Month")
@@ -189,19 +224,12 @@ func renderDeleteExpenses(m deleteEntriesModel, s string) string {
 	s += " | "
 	s += textStyle.Width(DefaultWidth).Render("Credit")
 	s += " | "
+	s += textStyle.Width(CategoryWidth).Render("Category")
+	s += " | "
 	s += textStyle.Width(DefaultWidth).Render("Selected")
 	s += "\n"
 
-	// slice entries
-	sliced_entries := m.entries
-	sliced_selected_entries := m.selected_entries
-	if len(m.found_entries) > num_entries_per_page {
-		end_idx := min(len(m.found_entries), (m.found_entries_page_idx+1)*num_entries_per_page)
-		sliced_entries = m.entries[m.found_entries_page_idx*num_entries_per_page : end_idx]
-		sliced_selected_entries = m.selected_entries[m.found_entries_page_idx*num_entries_per_page : end_idx]
-	}
-
-	for row, entry := range sliced_entries {
+	for row, entry := range m.entries {
 		line := selectDeleteEntryStyle(m, row).Width(DateWidth).Render(entry.Year)
 		line += " | "
 		line += selectDeleteEntryStyle(m, row).Width(DateWidth).Render(entry.Month)
@@ -214,10 +242,12 @@ func renderDeleteExpenses(m deleteEntriesModel, s string) string {
 		line += " | "
 		line += selectDeleteEntryStyle(m, row).Width(DefaultWidth).Render(entry.Credit)
 		line += " | "
+		line += selectDeleteEntryStyle(m, row).Width(CategoryWidth).Render(truncate(m.pager.entries[row].Category, CategoryWidth))
+		line += " | "
 
 		selected := " "
 		selected_entry_style := selectDeleteEntryStyle(m, row)
-		if len(sliced_selected_entries) > 0 && sliced_selected_entries[row] {
+		if _, ok := m.selected_entries[m.pager.entries[row].ID]; ok {
 			selected = "X"
 			selected_entry_style = selectedStyle
 		}
@@ -229,13 +259,7 @@ func renderDeleteExpenses(m deleteEntriesModel, s string) string {
 }
 
 func numDeleteSelectedEntries(m deleteEntriesModel) int {
-	num_selected := 0
-	for _, selected := range m.selected_entries {
-		if selected {
-			num_selected++
-		}
-	}
-	return num_selected
+	return len(m.selected_entries)
 }
 
 func renderDeleteActions(m deleteEntriesModel, s string) string {
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const NotesWidth = 60
+
+// Notes and attachments of one expense, opened from the update list.
+// The cursor moves over the notes, each attachment, then the path of a
+// file to attach.
+type entryDetailsModel struct {
+	entry       Expense
+	back        entryPager // update list page to return to
+	cursor      int
+	notes       string
+	attach_path string
+	feedback    string
+	failed      bool
+}
+
+func createEntryDetailsModel(entry Expense, back entryPager) entryDetailsModel {
+	return entryDetailsModel{
+		entry:    entry,
+		back:     back,
+		notes:    entry.Notes,
+		feedback: default_feedback,
+	}
+}
+
+func (m entryDetailsModel) attachRow() int {
+	return len(m.entry.Attachments) + 1
+}
+
+// Rereads the entry after a change was stored
+func reloadEntryDetails(m entryDetailsModel, feedback string) entryDetailsModel {
+	entry, err := mongoFindEntryByID(m.entry.ID)
+	if err != nil {
+		m.feedback = "Error loading entry: " + err.Error()
+		m.failed = true
+		return m
+	}
+	m.entry = entry
+	m.cursor = min(m.cursor, m.attachRow())
+	m.feedback = feedback
+	m.failed = false
+	return m
+}
+
+func (m entryDetailsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m entryDetailsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+
+		switch msg.String() {
+
+		case "up":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down":
+			if m.cursor < m.attachRow() {
+				m.cursor++
+			}
+
+		case "tab":
+			if m.cursor == m.attachRow() {
+				m.attach_path = completePath(m.attach_path, isAttachmentFile)
+			}
+
+		case "enter":
+			m = enterEntryDetails(m)
+
+		case "ctrl+d":
+			if m.cursor == 0 || m.cursor == m.attachRow() {
+				break
+			}
+			a := m.entry.Attachments[m.cursor-1]
+			if _, err := mongoRemoveEntryAttachment(m.entry.ID, a.Hash); err != nil {
+				m.feedback = "Error removing attachment: " + err.Error()
+				m.failed = true
+				break
+			}
+			m = reloadEntryDetails(m, "Removed "+a.Name+".")
+
+		case "esc", "ctrl+c":
+			return createUpdateEntriesModelAt(m.back.search, m.back.page_idx), nil
+
+		case "backspace":
+			if m.cursor == 0 {
+				m.notes = removeLastChar(m.notes)
+			} else if m.cursor == m.attachRow() {
+				m.attach_path = removeLastChar(m.attach_path)
+			}
+
+		default:
+			if m.cursor == 0 {
+				m.notes += msg.String()
+			} else if m.cursor == m.attachRow() {
+				m.attach_path += msg.String()
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// Saves the notes, opens the attachment or attaches the typed file,
+// depending on the row
+func enterEntryDetails(m entryDetailsModel) entryDetailsModel {
+	switch {
+	case m.cursor == 0:
+		if _, err := mongoSetEntryNotes(m.entry.ID, strings.TrimSpace(m.notes)); err != nil {
+			m.feedback = "Error saving notes: " + err.Error()
+			m.failed = true
+			return m
+		}
+		return reloadEntryDetails(m, "Notes saved.")
+
+	case m.cursor == m.attachRow():
+		if m.attach_path == "" {
+			return m
+		}
+		dir, err := attachmentsDir()
+		if err == nil {
+			var a attachment
+			a, err = storeAttachment(dir, expandHome(m.attach_path))
+			if err == nil {
+				_, err = mongoAddEntryAttachments(m.entry.ID, []attachment{a})
+			}
+		}
+		if err != nil {
+			m.feedback = "Error attaching file: " + err.Error()
+			m.failed = true
+			return m
+		}
+		name := filepath.Base(m.attach_path)
+		m.attach_path = ""
+		m = reloadEntryDetails(m, "Attached "+name+".")
+		m.cursor = m.attachRow()
+		return m
+	}
+
+	a := m.entry.Attachments[m.cursor-1]
+	if err := openAttachment(a); err != nil {
+		m.feedback = "Error opening " + a.Name + ": " + err.Error()
+		m.failed = true
+		return m
+	}
+	m.feedback = "Opened " + a.Name + "."
+	m.failed = false
+	return m
+}
+
+func (m entryDetailsModel) View() string {
+	s := selectedStyle.Width(HomeScreenWidth).Render("> Notes and attachments") + "\n"
+	s += textStyle.Render(dateOf(m.entry.Year, m.entry.Month, m.entry.Day).Format(date_layout)+"  "+
+		m.entry.Description+"  "+formatAmount(m.entry.Debit-m.entry.Credit)) + "\n\n"
+
+	style := inactiveStyle
+	if m.cursor == 0 {
+		style = selectedStyle
+	}
+	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Notes") +
+		style.Width(NotesWidth).Render(m.notes) + "\n"
+
+	s += textStyle.PaddingLeft(2).Render("Attachments") + "\n"
+	if len(m.entry.Attachments) == 0 {
+		s += inactiveStyle.PaddingLeft(4).Render("None") + "\n"
+	}
+	for idx, a := range m.entry.Attachments {
+		style := inactiveStyle
+		if m.cursor == idx+1 {
+			style = selectedStyle
+		}
+		s += style.PaddingLeft(4).Width(DescriptionWidth).Render(truncate(a.Name, DescriptionWidth-4)) +
+			inactiveStyle.Render(strconv.FormatInt(a.Size/1024, 10)+" KiB") + "\n"
+	}
+
+	style = inactiveStyle
+	if m.cursor == m.attachRow() {
+		style = selectedStyle
+	}
+	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Attach file") +
+		style.Width(NotesWidth).Render(m.attach_path) + "\n"
+
+	s += "\n" + textStyle.Render("Press enter to save the notes, open an attachment or attach the file (an image or PDF, tab completes).") + "\n"
+	s += textStyle.Render("Press ctrl+d to remove an attachment, esc to go back.") + "\n"
+	if m.failed {
+		s += errorStyle.Render(m.feedback) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
+	return s
+}
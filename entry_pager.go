@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strconv"
+)
+
+// One page of search results. Only the shown page is fetched from
+// storage; the total comes from a count query.
+type entryPager struct {
+	search   entrySearch
+	page_idx int
+	total    int64
+	entries  []Expense
+}
+
+// Fetches the page at page_idx, or the last page if there are fewer
+func loadEntryPage(search entrySearch, page_idx int) (entryPager, error) {
+	p := entryPager{search: search}
+
+	total, err := mongoCountMatchingEntries(search)
+	if err != nil {
+		return p, err
+	}
+	p.total = total
+	p.page_idx = max(0, min(page_idx, p.numPages()-1))
+
+	p.entries, err = mongoFindEntriesPage(search, int64(p.page_idx*num_entries_per_page), num_entries_per_page)
+	return p, err
+}
+
+func (p entryPager) numPages() int {
+	return int((p.total + num_entries_per_page - 1) / num_entries_per_page)
+}
+
+// Moves by delta pages, returning the pager unchanged past either end
+func (p entryPager) turn(delta int) (entryPager, error) {
+	page_idx := p.page_idx + delta
+	if page_idx < 0 || page_idx >= p.numPages() {
+		return p, nil
+	}
+	return loadEntryPage(p.search, page_idx)
+}
+
+// e.g. "Entries: 11-20 / 57"
+func (p entryPager) status() string {
+	first := p.page_idx*num_entries_per_page + 1
+	return "Entries: " + strconv.Itoa(first) + "-" + strconv.Itoa(first+len(p.entries)-1) + " / " + strconv.FormatInt(p.total, 10)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const csv_date_layout = "01/02/2006"
+const ofx_date_layout = "20060102"
+const ofx_currency = "CAD"
+
+type exportFormat struct {
+	name      string
+	extension string
+	write     func(w io.Writer, expenses []Expense) error
+}
+
+var export_formats = []exportFormat{
+	{name: "csv", extension: ".csv", write: writeExpensesCSV},
+	{name: "jsonl", extension: ".jsonl", write: writeExpensesJSONL},
+	{name: "ofx", extension: ".ofx", write: writeExpensesOFX},
+	{name: "ledger", extension: ".ledger", write: journalWriter(journal_ledger)},
+	{name: "hledger", extension: ".journal", write: journalWriter(journal_hledger)},
+	{name: "beancount", extension: ".beancount", write: journalWriter(journal_beancount)},
+}
+
+func findExportFormat(name string) (exportFormat, error) {
+	names := []string{}
+	for _, format := range export_formats {
+		if format.name == name {
+			return format, nil
+		}
+		names = append(names, format.name)
+	}
+	return exportFormat{}, fmt.Errorf("unknown format %q, expected one of: %s", name, strings.Join(names, ", "))
+}
+
+func exportExpensesToFile(filename string, format exportFormat, expenses []Expense) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := format.write(f, expenses); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func formatOptionalAmount(val float64) string {
+	if val == 0 {
+		return ""
+	}
+	return formatAmount(val)
+}
+
+// Same column layout as the csv files accepted by the Insert csv data
+// screen, followed by the notes and the paths of stored attachments
+func writeExpensesCSV(w io.Writer, expenses []Expense) error {
+	// without an attachments directory the paths are left empty rather
+	// than failing the export
+	dir, _ := attachmentsDir()
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"Date", "Description", "Debit", "Credit", "Total", "Notes", "Attachments"}); err != nil {
+		return err
+	}
+
+	for _, entry := range expenses {
+		record := []string{
+			dateOf(entry.Year, entry.Month, entry.Day).Format(csv_date_layout),
+			entry.Description,
+			formatOptionalAmount(entry.Debit),
+			formatOptionalAmount(entry.Credit),
+			formatOptionalAmount(entry.Total),
+			entry.Notes,
+			strings.Join(attachmentPaths(dir, entry), ";"),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// One JSON encoded expense per line
+func writeExpensesJSONL(w io.Writer, expenses []Expense) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range expenses {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type ofxTransaction struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	FITID  string `xml:"FITID"`
+	Name   string `xml:"NAME"`
+	Memo   string `xml:"MEMO,omitempty"`
+}
+
+type ofxDocument struct {
+	XMLName      xml.Name         `xml:"OFX"`
+	Status       int              `xml:"BANKMSGSRSV1>STMTTRNRS>STATUS>CODE"`
+	Severity     string           `xml:"BANKMSGSRSV1>STMTTRNRS>STATUS>SEVERITY"`
+	Currency     string           `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>CURDEF"`
+	Start        string           `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKTRANLIST>DTSTART"`
+	End          string           `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKTRANLIST>DTEND"`
+	Transactions []ofxTransaction `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKTRANLIST>STMTTRN"`
+}
+
+// OFX 2 (XML) bank statement. Debits are written as negative amounts.
+func writeExpensesOFX(w io.Writer, expenses []Expense) error {
+	sorted := make([]Expense, len(expenses))
+	copy(sorted, expenses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return dateKey(dateOf(sorted[i].Year, sorted[i].Month, sorted[i].Day)) <
+			dateKey(dateOf(sorted[j].Year, sorted[j].Month, sorted[j].Day))
+	})
+
+	doc := ofxDocument{Severity: "INFO", Currency: ofx_currency}
+	for idx, entry := range sorted {
+		posted := dateOf(entry.Year, entry.Month, entry.Day).Format(ofx_date_layout)
+		if idx == 0 {
+			doc.Start = posted
+		}
+		doc.End = posted
+
+		transaction := ofxTransaction{
+			Type:   "DEBIT",
+			Posted: posted,
+			Amount: strconv.FormatFloat(entry.Credit-entry.Debit, 'f', 2, 64),
+			FITID:  entry.ID.Hex(),
+			Name:   truncate(entry.Description, 32),
+		}
+		if entry.Credit > entry.Debit {
+			transaction.Type = "CREDIT"
+		}
+		memo := []string{}
+		if utf8.RuneCountInString(entry.Description) > 32 {
+			memo = append(memo, entry.Description)
+		}
+		if entry.Notes != "" {
+			memo = append(memo, entry.Notes)
+		}
+		transaction.Memo = strings.Join(memo, " - ")
+		doc.Transactions = append(doc.Transactions, transaction)
+	}
+	if len(sorted) == 0 {
+		doc.Start = time.Now().Format(ofx_date_layout)
+		doc.End = doc.Start
+	}
+
+	header := xml.Header +
+		`<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Keeps the first length runes of s, so multi-byte characters are never
+// cut in half
+func truncate(s string, length int) string {
+	if utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	return string([]rune(s)[:length])
+}
+
+// `budgie export` writes matching expenses to a file or stdout
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format_name := flags.String("format", "csv", "csv, jsonl, ofx, ledger, hledger or beancount")
+	out := flags.String("out", "", "output file (default stdout)")
+	build_search := addSearchFlags(flags)
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	format, err := findExportFormat(*format_name)
+	if err != nil {
+		return usageError{err}
+	}
+	search, err := build_search()
+	if err != nil {
+		return err
+	}
+
+	expenses, err := mongoFindEntries(search)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return format.write(os.Stdout, expenses)
+	}
+	if err := exportExpensesToFile(*out, format, expenses); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d entries to %s\n", len(expenses), *out)
+	return nil
+}
+
+// Either date may be left blank to leave that end of the range open.
+// A year or month (YYYY-MM) covers the whole year or month.
+func parseOptionalPeriod(from string, to string) (period, error) {
+	p := period{}
+	if from != "" {
+		first, _, err := parseQueryDate(from)
+		if err != nil {
+			return p, errors.New("invalid from date, format: YYYY, YYYY-MM, YYYY-MM-DD, this-month, last-year, etc.")
+		}
+		p.from = first
+	}
+	if to != "" {
+		_, last, err := parseQueryDate(to)
+		if err != nil {
+			return p, errors.New("invalid to date, format: YYYY, YYYY-MM, YYYY-MM-DD, this-month, last-year, etc.")
+		}
+		p.to = last
+	}
+	if !p.from.IsZero() && !p.to.IsZero() && p.to.Before(p.from) {
+		return p, errors.New("to date is before from date")
+	}
+	return p, nil
+}
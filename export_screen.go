@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const default_export_filename = "budgie_export"
+
+type exportScreenModel struct {
+	expenses   []Expense
+	format_idx int
+	filename   string
+	feedback   string
+	failed     bool
+}
+
+func createExportScreenModel(expenses []Expense) exportScreenModel {
+	return exportScreenModel{
+		expenses: expenses,
+		filename: default_export_filename + export_formats[0].extension,
+		feedback: default_feedback,
+	}
+}
+
+func (m exportScreenModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m exportScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+
+		switch msg.String() {
+
+		case "left":
+			m = selectExportFormat(m, (m.format_idx+len(export_formats)-1)%len(export_formats))
+		case "right", "tab":
+			m = selectExportFormat(m, (m.format_idx+1)%len(export_formats))
+
+		case "backspace":
+			m.filename = removeLastChar(m.filename)
+
+		case "enter":
+			if m.filename == "" {
+				m.feedback = "Enter a filename to export to."
+				m.failed = true
+				break
+			}
+			err := exportExpensesToFile(m.filename, export_formats[m.format_idx], m.expenses)
+			if err != nil {
+				m.feedback = "Error exporting entries: " + err.Error()
+				m.failed = true
+			} else {
+				m.feedback = "Exported " + strconv.Itoa(len(m.expenses)) + " entries to " + m.filename
+				m.failed = false
+			}
+
+		case "ctrl+c":
+			return createHomeScreenModel(), nil
+
+		default:
+			m.filename += msg.String()
+		}
+	}
+
+	return m, nil
+}
+
+// Switches format, keeping the filename extension in step with it
+func selectExportFormat(m exportScreenModel, idx int) exportScreenModel {
+	old_extension := export_formats[m.format_idx].extension
+	m.format_idx = idx
+	if strings.HasSuffix(m.filename, old_extension) {
+		m.filename = strings.TrimSuffix(m.filename, old_extension) + export_formats[idx].extension
+	}
+	return m
+}
+
+func (m exportScreenModel) View() string {
+	s := selectedStyle.Width(HomeScreenWidth).Render("> Export entries") + "\n"
+	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Entries: ") +
+		inactiveStyle.PaddingLeft(2).PaddingRight(2).Render(strconv.Itoa(len(m.expenses))) + "\n"
+
+	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Format: ")
+	for idx, format := range export_formats {
+		if idx == m.format_idx {
+			s += selectedStyle.PaddingLeft(1).PaddingRight(1).Render(format.name)
+		} else {
+			s += inactiveStyle.PaddingLeft(1).PaddingRight(1).Render(format.name)
+		}
+	}
+	s += "\n"
+
+	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Filename: ") +
+		errorStyle.PaddingLeft(2).PaddingRight(2).Render(m.filename) + "\n"
+
+	s += "\n" + textStyle.Render("Press < or > to change format, enter to export.") + "\n"
+	if m.failed {
+		s += errorStyle.Render(m.feedback) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWriteExpensesCSVWithoutAttachmentsDir(t *testing.T) {
+	// no config directory, so attachmentsDir fails
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "")
+	if _, err := attachmentsDir(); err == nil {
+		t.Skip("attachments directory found without HOME")
+	}
+
+	expenses := []Expense{{
+		Year: 2024, Month: 7, Day: 15, Description: "TIM HORTONS", Debit: 4.5, Notes: "team coffee",
+		Attachments: []attachment{{Name: "receipt.jpg", Hash: "3fa2e1", Size: 1024}},
+	}}
+	var out bytes.Buffer
+	if err := writeExpensesCSV(&out, expenses); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Date,Description,Debit,Credit,Total,Notes,Attachments\n" +
+		dateOf(2024, 7, 15).Format(csv_date_layout) + ",TIM HORTONS,4.50,,,team coffee,\n"
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s      string
+		length int
+		want   string
+	}{
+		{s: "TIM HORTONS", length: 32, want: "TIM HORTONS"},
+		{s: "TIM HORTONS", length: 3, want: "TIM"},
+		{s: "CAFÉ ÉTOILE", length: 4, want: "CAFÉ"},
+		{s: "寿司レストラン", length: 2, want: "寿司"},
+	}
+
+	for _, test := range tests {
+		if got := truncate(test.s, test.length); got != test.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", test.s, test.length, got, test.want)
+		}
+	}
+}
+
+func TestWriteExpensesOFXMultiByteName(t *testing.T) {
+	description := strings.Repeat("é", 40)
+	var out bytes.Buffer
+	if err := writeExpensesOFX(&out, []Expense{{Year: 2024, Month: 7, Day: 15, Description: description, Debit: 4.5}}); err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.Valid(out.Bytes()) {
+		t.Fatal("OFX export is not valid UTF-8")
+	}
+	if !strings.Contains(out.String(), "<NAME>"+strings.Repeat("é", 32)+"</NAME>") {
+		t.Errorf("NAME is not the first 32 characters:\n%s", out.String())
+	}
+}
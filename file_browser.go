@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const browser_time_layout = "2006-01-02 15:04"
+
+// A directory or statement file shown in the file browser
+type browserEntry struct {
+	name     string
+	path     string
+	is_dir   bool
+	size     int64
+	mod_time time.Time
+}
+
+// Lists the subdirectories and supported statement files of dir,
+// directories first. Hidden files are left out. The parent directory
+// is listed first as "..".
+func listStatementDir(dir string) ([]browserEntry, error) {
+	dir_entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []browserEntry{}
+	files := []browserEntry{}
+	for _, dir_entry := range dir_entries {
+		name := dir_entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		info, err := dir_entry.Info()
+		if err != nil {
+			continue
+		}
+
+		entry := browserEntry{
+			name:     name,
+			path:     filepath.Join(dir, name),
+			is_dir:   info.IsDir(),
+			size:     info.Size(),
+			mod_time: info.ModTime(),
+		}
+		if entry.is_dir {
+			dirs = append(dirs, entry)
+		} else if isSupportedStatementFile(name) {
+			files = append(files, entry)
+		}
+	}
+
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].name < dirs[j].name })
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+
+	listing := []browserEntry{}
+	if parent := filepath.Dir(dir); parent != dir {
+		listing = append(listing, browserEntry{name: "..", path: parent, is_dir: true})
+	}
+	listing = append(listing, dirs...)
+	return append(listing, files...), nil
+}
+
+// Completes the last path component of path as far as it is unambiguous,
+// like a shell does on tab. Directories get a trailing separator; files
+// are only completed to if is_wanted accepts their name.
+func completePath(path string, is_wanted func(name string) bool) string {
+	expanded := expandHome(path)
+	dir, prefix := filepath.Split(expanded)
+	if dir == "" {
+		dir = "."
+	}
+
+	dir_entries, err := os.ReadDir(dir)
+	if err != nil {
+		return path
+	}
+
+	matches := []os.DirEntry{}
+	for _, dir_entry := range dir_entries {
+		name := dir_entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		if !dir_entry.IsDir() && !is_wanted(name) {
+			continue
+		}
+		matches = append(matches, dir_entry)
+	}
+	if len(matches) == 0 {
+		return path
+	}
+
+	common := matches[0].Name()
+	for _, match := range matches[1:] {
+		common = commonPrefix(common, match.Name())
+	}
+
+	completion := common[len(prefix):]
+	if len(matches) == 1 && matches[0].IsDir() {
+		completion += string(filepath.Separator)
+	}
+	return path + completion
+}
+
+func commonPrefix(a string, b string) string {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return a[:i]
+		}
+	}
+	return a[:n]
+}
+
+func formatFileSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return strconv.FormatFloat(float64(size)/(1<<20), 'f', 1, 64) + " MB"
+	case size >= 1<<10:
+		return strconv.FormatFloat(float64(size)/(1<<10), 'f', 1, 64) + " KB"
+	default:
+		return strconv.FormatInt(size, 10) + " B"
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompletePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"receipt.pdf", "statement.ofx", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "scans"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		typed     string
+		is_wanted func(string) bool
+		want      string
+	}{
+		{name: "attachment", typed: "rec", is_wanted: isAttachmentFile, want: "receipt.pdf"},
+		{name: "statement", typed: "sta", is_wanted: isSupportedStatementFile, want: "statement.ofx"},
+		{name: "attachment not a statement", typed: "rec", is_wanted: isSupportedStatementFile, want: "rec"},
+		{name: "neither", typed: "no", is_wanted: isAttachmentFile, want: "no"},
+		{name: "directory", typed: "sc", is_wanted: isAttachmentFile, want: "scans" + string(filepath.Separator)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := completePath(filepath.Join(dir, test.typed), test.is_wanted)
+			if want := dir + string(filepath.Separator) + test.want; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"strconv"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -9,19 +10,75 @@ import (
 )
 
 const FindEntryLabelWidth = 20
+const FindQueryWidth = 60
+
+// date and amount ranges, then the query bar, come after the expense fields
+const (
+	find_from       = num_expense_search_fields + iota
+	find_to         = num_expense_search_fields + iota
+	find_min_debit  = num_expense_search_fields + iota
+	find_max_debit  = num_expense_search_fields + iota
+	find_min_credit = num_expense_search_fields + iota
+	find_max_credit = num_expense_search_fields + iota
+	find_min_amount = num_expense_search_fields + iota
+	find_max_amount = num_expense_search_fields + iota
+	find_query      = num_expense_search_fields + iota
+	num_find_fields = num_expense_search_fields + iota
+)
+
+// parseSearch parameters of the fields, used to save searches
+var find_field_params = [num_find_fields]string{
+	expense_year:        "year",
+	expense_month:       "month",
+	expense_day:         "day",
+	expense_description: "description",
+	expense_debit:       "debit",
+	expense_credit:      "credit",
+	find_from:           "from",
+	find_to:             "to",
+	find_min_debit:      "min_debit",
+	find_max_debit:      "max_debit",
+	find_min_credit:     "min_credit",
+	find_max_credit:     "max_credit",
+	find_min_amount:     "min_amount",
+	find_max_amount:     "max_amount",
+	find_query:          "q",
+}
+
+var find_field_labels = [num_find_fields]string{
+	expense_year:        "Year: ",
+	expense_month:       "Month: ",
+	expense_day:         "Day: ",
+	expense_description: "Description: ",
+	expense_debit:       "Debit: ",
+	expense_credit:      "Credit: ",
+	find_from:           "From date: ",
+	find_to:             "To date: ",
+	find_min_debit:      "Min debit: ",
+	find_max_debit:      "Max debit: ",
+	find_min_credit:     "Min credit: ",
+	find_max_credit:     "Max credit: ",
+	find_min_amount:     "Min amount: ",
+	find_max_amount:     "Max amount: ",
+	find_query:          "Query: ",
+}
 
 type findEntryModel struct {
-	fields          [num_expense_search_fields]string
-	validated       [num_expense_search_fields]bool
+	fields          [num_find_fields]string
+	validated       [num_find_fields]bool
 	feedback        string
 	search_cursor   int
 	entry_to_search Expense
-	found_entries   []Expense
+	query           queryNode
 	action          action
+	naming          bool // typing the name to save the search under
+	save_name       string
 }
 
+// Empty fields match everything, so they start out validated and enter
+// on any field searches once the edited fields are valid
 func createFindEntryModel(action action) findEntryModel {
-	return findEntryModel{
+	m := findEntryModel{
 		entry_to_search: Expense{
 			Month:  invalid,
 			Day:    invalid,
@@ -29,10 +86,13 @@ func createFindEntryModel(action action) findEntryModel {
 			Debit:  invalid,
 			Credit: invalid,
 		},
-		validated: [num_expense_search_fields]bool{false, false, false, false, false, false},
-		feedback:  default_feedback,
-		action:    action,
+		feedback: default_feedback,
+		action:   action,
+	}
+	for i := range m.validated {
+		m.validated[i] = true
 	}
+	return m
 }
 
 func (m findEntryModel) Init() tea.Cmd {
@@ -44,15 +104,24 @@ func (m findEntryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 
+		if m.naming {
+			return updateSaveSearchName(m, msg)
+		}
+
 		switch msg.String() {
 
+		case "ctrl+s":
+			m.naming = true
+			m.save_name = ""
+			m.feedback = "Name of the saved search: "
+
 		case "up":
 			if m.search_cursor > expense_year {
 				m.search_cursor--
 			}
 
 		case "down":
-			if m.search_cursor < expense_credit {
+			if m.search_cursor < find_query {
 				m.search_cursor++
 			}
 
@@ -60,6 +129,7 @@ func (m findEntryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			sz := len(m.fields[m.search_cursor])
 			if sz >= 1 {
 				m.fields[m.search_cursor] = m.fields[m.search_cursor][:sz-1]
+				m.validated[m.search_cursor] = false
 			}
 
 		case "enter":
@@ -156,24 +226,72 @@ func (m findEntryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if err == nil {
 						m.entry_to_search.Credit = val
 						m.validated[m.search_cursor] = true
+						m.search_cursor++
 						m.feedback = default_feedback
 					} else {
 						m.validated[m.search_cursor] = false
-						m.feedback = "Invalid debit amount!"
+						m.feedback = "Invalid credit amount!"
 					}
 				} else {
 					m.entry_to_search.Credit = invalid
 					m.validated[m.search_cursor] = true
+					m.search_cursor++
 					m.feedback = default_feedback
 				}
+			case find_from, find_to:
+				_, _, err := parseQueryDate(m.fields[m.search_cursor])
+				if m.fields[m.search_cursor] == "" || err == nil {
+					m.validated[m.search_cursor] = true
+					m.search_cursor++
+					m.feedback = default_feedback
+				} else {
+					m.validated[m.search_cursor] = false
+					m.feedback = "Invalid date! Format: YYYY, YYYY-MM, YYYY-MM-DD, this-month, last-year, etc."
+				}
+			case find_min_debit, find_max_debit, find_min_credit, find_max_credit, find_min_amount, find_max_amount:
+				_, err := strconv.ParseFloat(m.fields[m.search_cursor], 64)
+				if m.fields[m.search_cursor] == "" || err == nil {
+					m.validated[m.search_cursor] = true
+					m.search_cursor++
+					m.feedback = default_feedback
+				} else {
+					m.validated[m.search_cursor] = false
+					m.feedback = "Invalid amount!"
+				}
+			case find_query:
+				query, err := parseQuery(m.fields[m.search_cursor])
+				if err == nil {
+					m.query = query
+					m.validated[m.search_cursor] = true
+					m.feedback = default_feedback
+				} else {
+					m.validated[m.search_cursor] = false
+					m.feedback = "Invalid query " + err.Error()
+				}
 			}
 			if allValid(m) {
-				m.found_entries = mongoFindMatchingEntries(m.entry_to_search)
+				search, field, err := buildFindSearch(m)
+				if err != nil {
+					// the end of a range is before its start
+					m.validated[field] = false
+					m.search_cursor = field
+					m.feedback = "Invalid range, " + err.Error()
+					return m, nil
+				}
+
 				// TODO: transition to found_entries_screen
 				if m.action.action_text == "delete" {
-					return createDeleteEntriesModel(m.found_entries, m.entry_to_search), nil
+					return createDeleteEntriesModel(search), nil
+				} else if m.action.action_text == "export" {
+					// exports every match, so all of them are fetched
+					found_entries, err := mongoFindEntries(search)
+					if err != nil {
+						m.feedback = "Error searching entries: " + err.Error()
+						return m, nil
+					}
+					return createExportScreenModel(found_entries), nil
 				} else {
-					return createUpdateEntriesModel(m.found_entries, m.entry_to_search), nil
+					return createUpdateEntriesModel(search), nil
 				}
 			}
 
@@ -181,6 +299,7 @@ func (m findEntryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return createHomeScreenModel(), nil
 		default:
 			m.fields[m.search_cursor] += msg.String()
+			m.validated[m.search_cursor] = false
 		}
 	}
 
@@ -194,24 +313,90 @@ func (m findEntryModel) View() string {
 }
 
 func renderSearchBox(m findEntryModel, s string) string {
-	s += textStyle.PaddingRight(1).Render("Enter in details of entry to search for. Leave blank to search all.") + "\n"
-	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Year: ") +
-		selectSearchBoxStyle(m, expense_year).Width(FindEntryLabelWidth).Render(m.fields[expense_year]) + "\n"
-	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Month: ") +
-		selectSearchBoxStyle(m, expense_month).Width(FindEntryLabelWidth).Render(m.fields[expense_month]) + "\n"
-	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Day: ") +
-		selectSearchBoxStyle(m, expense_day).Width(FindEntryLabelWidth).Render(m.fields[expense_day]) + "\n"
-	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Description: ") +
-		selectSearchBoxStyle(m, expense_description).Width(FindEntryLabelWidth).Render(m.fields[expense_description]) + "\n"
-	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Debit: ") +
-		selectSearchBoxStyle(m, expense_debit).Width(FindEntryLabelWidth).Render(m.fields[expense_debit]) + "\n"
-	s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render("Credit: ") +
-		selectSearchBoxStyle(m, expense_credit).Width(FindEntryLabelWidth).Render(m.fields[expense_credit]) + "\n"
-	s += textStyle.Render(m.feedback) + "\n"
+	s += textStyle.PaddingRight(1).Render("Enter in details of entry to search for. Leave blank to search all, press enter to search.") + "\n"
+	for field, label := range find_field_labels {
+		width := FindEntryLabelWidth
+		if field == find_query {
+			width = FindQueryWidth
+		}
+		s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render(label) +
+			selectSearchBoxStyle(m, field).Width(width).Render(m.fields[field]) + "\n"
+	}
+	s += inactiveStyle.PaddingLeft(2).Render(`e.g. desc:tim date:2024-07-01..2024-07-31 debit>50 category:food -tag:reimbursed`) + "\n"
+	s += textStyle.Render("Press ctrl+s to save the search.") + "\n"
+	if m.naming {
+		s += textStyle.Render(m.feedback) + selectedStyle.PaddingLeft(1).PaddingRight(1).Render(m.save_name) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
 
 	return s
 }
 
+// Saves the fields under the typed name on enter, esc cancels
+func updateSaveSearchName(m findEntryModel, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.naming = false
+		m.feedback = default_feedback
+	case "backspace":
+		m.save_name = removeLastChar(m.save_name)
+	case "enter":
+		name := strings.TrimSpace(m.save_name)
+		if name == "" {
+			break
+		}
+
+		saved := savedSearch{Name: name, Params: map[string]string{}}
+		for field, param := range find_field_params {
+			if value := strings.TrimSpace(m.fields[field]); value != "" {
+				saved.Params[param] = value
+			}
+		}
+		m.naming = false
+		if _, err := saved.search(); err != nil {
+			m.feedback = "Cannot save an invalid search: " + err.Error()
+			break
+		}
+		if err := mongoSaveSearch(saved); err != nil {
+			m.feedback = "Error saving search: " + err.Error()
+			break
+		}
+		m.feedback = "Saved search " + name + "."
+	default:
+		m.save_name += msg.String()
+	}
+	return m, nil
+}
+
+// Returns the field to fix if a range ends before it starts
+func buildFindSearch(m findEntryModel) (entrySearch, int, error) {
+	search := entrySearch{entry: m.entry_to_search, query: m.query}
+	if hasTextTerm(m.query) {
+		search.sort.column = sort_relevance
+	}
+	var err error
+
+	search.date_range, err = parseOptionalPeriod(m.fields[find_from], m.fields[find_to])
+	if err != nil {
+		return search, find_to, err
+	}
+	search.debit_range, err = parseAmountRange(m.fields[find_min_debit], m.fields[find_max_debit], "debit")
+	if err != nil {
+		return search, find_max_debit, err
+	}
+	search.credit_range, err = parseAmountRange(m.fields[find_min_credit], m.fields[find_max_credit], "credit")
+	if err != nil {
+		return search, find_max_credit, err
+	}
+	search.amount_range, err = parseAmountRange(m.fields[find_min_amount], m.fields[find_max_amount], "amount")
+	if err != nil {
+		return search, find_max_amount, err
+	}
+
+	return search, 0, nil
+}
+
 func selectSearchBoxStyle(m findEntryModel, index int) lipgloss.Style {
 	if m.search_cursor == index {
 		return selectedStyle.PaddingLeft(2).PaddingRight(2)
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const forecast_months = 3         // months forecast after the current one
+const forecast_history_months = 6 // full months averaged per category
+const forecast_actual_months = 3  // past month ends shown for comparison
+
+// Shown for entries without an account
+const no_account = "(no account)"
+
+// Balance of an account at the end of a day. Forecast points are
+// estimates; the others are computed from stored entries.
+type balancePoint struct {
+	Date     string  `json:"date"`
+	Balance  float64 `json:"balance"`
+	Forecast bool    `json:"forecast"`
+}
+
+type accountForecast struct {
+	Account string         `json:"account"`
+	Balance float64        `json:"balance"` // today
+	Points  []balancePoint `json:"points"`
+
+	// excludes recurring entries and subscriptions, which are forecast by date
+	CategoryAverages []categoryAverage `json:"category_averages"`
+}
+
+// Average monthly credits minus debits of a category
+type categoryAverage struct {
+	Category string  `json:"category"`
+	Average  float64 `json:"average"`
+}
+
+// A future change of balance
+type forecastFlow struct {
+	date   time.Time
+	amount float64
+}
+
+func buildForecast(now time.Time) ([]accountForecast, error) {
+	today := dateOf(now.Year(), int(now.Month()), now.Day())
+	this_month := dateOf(now.Year(), int(now.Month()), 1)
+
+	// only the months averaged per category are loaded, balances are
+	// summed by the database
+	recent, err := mongoFindEntries(entrySearch{
+		entry:      matchAllEntries(),
+		date_range: period{from: this_month.AddDate(0, -forecast_history_months, 0), to: this_month.AddDate(0, 0, -1)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	balances, err := mongoAccountBalances(actualBalanceDates(this_month, today))
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, err := findSubscriptions(now)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := mongoFindRecurringTemplates()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return forecastBalances(recent, balances, subscriptions, templates, cfg.OpeningBalances, now), nil
+}
+
+// The last forecast_actual_months month ends and today
+func actualBalanceDates(this_month time.Time, today time.Time) []time.Time {
+	dates := []time.Time{}
+	for n := forecast_actual_months; n > 0; n-- {
+		dates = append(dates, this_month.AddDate(0, -n+1, -1))
+	}
+	return append(dates, today)
+}
+
+// Forecasts the balance of each account at the end of this month and the
+// next forecast_months months from scheduled recurring entries, detected
+// subscriptions and the average monthly spending per category. recent
+// holds the entries of the months averaged, balances the stored balance
+// of each account on the actualBalanceDates.
+func forecastBalances(recent []Expense, balances map[string][]float64, subscriptions []subscription, templates []recurringTemplate, opening map[string]float64, now time.Time) []accountForecast {
+	today := dateOf(now.Year(), int(now.Month()), now.Day())
+	this_month := dateOf(now.Year(), int(now.Month()), 1)
+	horizon := this_month.AddDate(0, forecast_months+1, -1)
+
+	forecasts := map[string]*accountForecast{}
+	forecastFor := func(account string) *accountForecast {
+		if account == "" {
+			account = no_account
+		}
+		if forecasts[account] == nil {
+			forecasts[account] = &accountForecast{Account: account}
+		}
+		return forecasts[account]
+	}
+	for account := range opening {
+		forecastFor(account)
+	}
+
+	balance_dates := actualBalanceDates(this_month, today)
+	actual := map[string][]float64{}
+	for account, account_balances := range balances {
+		account = forecastFor(account).Account
+		actual[account] = account_balances
+	}
+
+	// payees forecast by date are left out of the averages
+	scheduled := map[string]bool{}
+	flows := map[string][]forecastFlow{}
+
+	for _, template := range templates {
+		scheduled[payeeKey(template.Description)] = true
+		forecastFor(template.Account)
+
+		upcoming := template
+		if template.StartDate <= today.Format(date_layout) {
+			upcoming.GeneratedUntil = today.Format(date_layout)
+		}
+		dates, err := recurringDueDates(upcoming, horizon)
+		if err != nil {
+			continue
+		}
+		for _, date := range dates {
+			account := forecastFor(template.Account).Account
+			flows[account] = append(flows[account], forecastFlow{date: date, amount: template.Credit - template.Debit})
+		}
+	}
+
+	for _, found := range subscriptions {
+		key := payeeKey(found.Payee)
+		if found.Ended || scheduled[key] {
+			continue
+		}
+		scheduled[key] = true
+
+		c, ok := findCadence(found.Cadence)
+		next, err := time.Parse(date_layout, found.NextCharge)
+		if !ok || err != nil {
+			continue
+		}
+		// a missed charge is assumed to be skipped, not late
+		for !next.After(today) {
+			next = c.after(next, 1)
+		}
+		account := forecastFor(found.Account).Account
+		for ; !next.After(horizon); next = c.after(next, 1) {
+			flows[account] = append(flows[account], forecastFlow{date: next, amount: -found.Amount})
+		}
+	}
+
+	history_from := this_month.AddDate(0, -forecast_history_months, 0)
+	averages := map[string]map[string]float64{}
+	for _, entry := range recent {
+		date := dateOf(entry.Year, entry.Month, entry.Day)
+		if date.Before(history_from) || !date.Before(this_month) || scheduled[payeeKey(entry.Description)] {
+			continue
+		}
+		account := forecastFor(entry.Account).Account
+		category := entry.Category
+		if category == "" {
+			category = uncategorized
+		}
+		if averages[account] == nil {
+			averages[account] = map[string]float64{}
+		}
+		averages[account][category] += (entry.Credit - entry.Debit) / forecast_history_months
+	}
+
+	result := []accountForecast{}
+	for account, forecast := range forecasts {
+		forecast.CategoryAverages = sortedCategoryAverages(averages[account])
+		forecast.Points = actualBalancePoints(balance_dates, actual[account], opening[account])
+		forecast.Balance = forecast.Points[len(forecast.Points)-1].Balance
+		forecast.Points = append(forecast.Points,
+			forecastBalancePoints(*forecast, flows[account], today, this_month)...)
+		result = append(result, *forecast)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Account < result[j].Account })
+	return result
+}
+
+// Balance points on dates from the stored balances, which are missing
+// for accounts without entries
+func actualBalancePoints(dates []time.Time, balances []float64, opening float64) []balancePoint {
+	points := []balancePoint{}
+	for idx, date := range dates {
+		balance := opening
+		if idx < len(balances) {
+			balance += balances[idx]
+		}
+		points = append(points, balancePoint{Date: date.Format(date_layout), Balance: balance})
+	}
+	return points
+}
+
+// Balances at the end of this month and the following months. Category
+// averages are spread evenly over the days of each month.
+func forecastBalancePoints(forecast accountForecast, flows []forecastFlow, today time.Time, this_month time.Time) []balancePoint {
+	monthly_average := 0.0
+	for _, average := range forecast.CategoryAverages {
+		monthly_average += average.Average
+	}
+
+	points := []balancePoint{}
+	balance := forecast.Balance
+	from := today
+
+	for n := 1; n <= forecast_months+1; n++ {
+		month_end := this_month.AddDate(0, n, -1)
+		days_in_month := float64(month_end.Day())
+		days := month_end.Sub(from).Hours() / 24
+
+		balance += monthly_average * days / days_in_month
+		for _, flow := range flows {
+			if flow.date.After(from) && !flow.date.After(month_end) {
+				balance += flow.amount
+			}
+		}
+
+		points = append(points, balancePoint{Date: month_end.Format(date_layout), Balance: balance, Forecast: true})
+		from = month_end
+	}
+	return points
+}
+
+// Largest spending first
+func sortedCategoryAverages(averages map[string]float64) []categoryAverage {
+	sorted := []categoryAverage{}
+	for category, average := range averages {
+		sorted = append(sorted, categoryAverage{Category: category, Average: average})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Average != sorted[j].Average {
+			return sorted[i].Average < sorted[j].Average
+		}
+		return sorted[i].Category < sorted[j].Category
+	})
+	return sorted
+}
+
+// `budgie forecast` prints the balance forecast of each account
+func runForecast(args []string) error {
+	flags := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	as_json := flags.Bool("json", false, "print JSON instead of text")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	forecasts, err := buildForecast(time.Now())
+	if err != nil {
+		return err
+	}
+
+	if *as_json {
+		return writeJSONOutput(forecasts)
+	}
+
+	for _, forecast := range forecasts {
+		fmt.Println(forecast.Account)
+		for _, point := range forecast.Points {
+			kind := "actual"
+			if point.Forecast {
+				kind = "forecast"
+			}
+			fmt.Printf("  %s %12s  %s\n", point.Date, formatAmount(point.Balance), kind)
+		}
+	}
+	return nil
+}
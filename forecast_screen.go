@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type forecastScreenModel struct {
+	forecasts   []accountForecast
+	account_idx int
+	feedback    string
+	failed      bool
+}
+
+func createForecastScreenModel() forecastScreenModel {
+	m := forecastScreenModel{
+		feedback: default_feedback,
+	}
+
+	forecasts, err := buildForecast(time.Now())
+	if err != nil {
+		m.feedback = "Error building forecast: " + err.Error()
+		m.failed = true
+	}
+	m.forecasts = forecasts
+
+	return m
+}
+
+func (m forecastScreenModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m forecastScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+
+		switch msg.String() {
+
+		case "left":
+			if m.account_idx > 0 {
+				m.account_idx--
+			}
+		case "right":
+			if m.account_idx < len(m.forecasts)-1 {
+				m.account_idx++
+			}
+
+		case "ctrl+c":
+			return createHomeScreenModel(), nil
+		}
+	}
+
+	return m, nil
+}
+
+func (m forecastScreenModel) View() string {
+	s := selectedStyle.Width(HomeScreenWidth).Render("> Cash-flow forecast") + "\n"
+
+	if len(m.forecasts) == 0 {
+		s += inactiveStyle.Width(DescriptionWidth).Render("No accounts to forecast") + "\n"
+	} else {
+		forecast := m.forecasts[m.account_idx]
+		s += textStyle.PaddingLeft(2).PaddingRight(2).Render(
+			"< "+forecast.Account+" ("+strconv.Itoa(m.account_idx+1)+"/"+strconv.Itoa(len(m.forecasts))+") >") + "\n\n"
+		s = renderBalancePoints(forecast, s)
+		s = renderCategoryAverages(forecast, s)
+	}
+
+	s += "\n" + textStyle.Render("Press < or > to switch accounts.") + "\n"
+	if m.failed {
+		s += errorStyle.Render(m.feedback) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
+	return s
+}
+
+func renderBalancePoints(forecast accountForecast, s string) string {
+	s += textStyle.Width(DefaultWidth).Render("Date")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Balance")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("")
+	s += "\n"
+
+	for _, point := range forecast.Points {
+		style := selectedStyle
+		kind := "actual"
+		if point.Forecast {
+			style = questionStyle
+			kind = "forecast"
+		}
+
+		line := style.Width(DefaultWidth).Render(point.Date)
+		line += " | "
+		line += style.Width(DefaultWidth).Render(formatAmount(point.Balance))
+		line += " | "
+		line += style.Width(DefaultWidth).Render(kind)
+		s += line + "\n"
+	}
+
+	s += "\n" + selectedStyle.Width(LegendWidth).Render("Actual - computed from stored entries") + "\n"
+	s += questionStyle.Width(LegendWidth).Render("Forecast - estimated, may change") + "\n\n"
+	return s
+}
+
+func renderCategoryAverages(forecast accountForecast, s string) string {
+	s += textStyle.PaddingRight(2).Render("Average per month over the last "+strconv.Itoa(forecast_history_months)+" months") + "\n"
+
+	for _, average := range forecast.CategoryAverages {
+		s += inactiveStyle.Width(ChartLabelWidth).Render(truncate(average.Category, ChartLabelWidth)) +
+			" " + inactiveStyle.Width(DefaultWidth).Render(formatAmount(average.Average)) + "\n"
+	}
+	return s
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForecastBalances(t *testing.T) {
+	now := time.Date(2024, 7, 10, 9, 0, 0, 0, time.UTC)
+	recent := []Expense{
+		{Year: 2024, Month: 1, Day: 5, Description: "GROCER", Debit: 300, Category: "Groceries", Account: "chequing"},
+		{Year: 2024, Month: 6, Day: 5, Description: "GROCER", Debit: 300, Category: "Groceries", Account: "chequing"},
+		{Year: 2024, Month: 6, Day: 20, Description: "NETFLIX", Debit: 15, Account: "chequing"},
+	}
+	// April, May and June month ends, then today
+	balances := map[string][]float64{
+		"chequing": {1000, 1200, 900, 850},
+		"":         {-5, -5, -5, -5},
+	}
+	subscriptions := []subscription{{Payee: "NETFLIX", Account: "chequing", Cadence: "monthly", Amount: 15, NextCharge: "2024-07-20"}}
+	opening := map[string]float64{"chequing": 100, "savings": 5000}
+
+	forecasts := forecastBalances(recent, balances, subscriptions, nil, opening, now)
+	if len(forecasts) != 3 {
+		t.Fatalf("got %d accounts, want 3: %+v", len(forecasts), forecasts)
+	}
+
+	chequing := forecasts[1]
+	if chequing.Account != "chequing" || chequing.Balance != 950 {
+		t.Fatalf("got %s with balance %v, want chequing with 950", chequing.Account, chequing.Balance)
+	}
+	want_actual := []balancePoint{{Date: "2024-04-30", Balance: 1100}, {Date: "2024-05-31", Balance: 1300}, {Date: "2024-06-30", Balance: 1000}, {Date: "2024-07-10", Balance: 950}}
+	for idx, want := range want_actual {
+		if chequing.Points[idx] != want {
+			t.Errorf("point %d = %+v, want %+v", idx, chequing.Points[idx], want)
+		}
+	}
+
+	// the subscription is forecast by date, not averaged
+	if len(chequing.CategoryAverages) != 1 || chequing.CategoryAverages[0].Average != -100 {
+		t.Errorf("averages = %+v, want groceries at -100", chequing.CategoryAverages)
+	}
+	july_end := chequing.Points[len(want_actual)]
+	want_july := 950 - 100*21.0/31 - 15
+	if !july_end.Forecast || july_end.Date != "2024-07-31" || july_end.Balance-want_july > 1e-9 || want_july-july_end.Balance > 1e-9 {
+		t.Errorf("July forecast = %+v, want %v", july_end, want_july)
+	}
+
+	if forecasts[0].Account != no_account || forecasts[0].Balance != -5 {
+		t.Errorf("got %+v, want %s with balance -5", forecasts[0], no_account)
+	}
+	if forecasts[2].Account != "savings" || forecasts[2].Balance != 5000 {
+		t.Errorf("got %+v, want savings with balance 5000", forecasts[2])
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -10,6 +11,7 @@ type homeScreenModel struct {
 	choices  []string         // items on list
 	cursor   int              // which item our cursor is pointing at
 	selected map[int]struct{} // which items are selected
+	feedback string
 }
 
 const HomeScreenWidth = 30
@@ -19,22 +21,40 @@ const (
 	insertEntry   = iota
 	updateEntry   = iota
 	deleteEntry   = iota
+	reports       = iota
+	trends        = iota
+	comparison    = iota
+	exportEntries = iota
+	subscriptions = iota
+	forecast      = iota
+	savedSearches = iota
+	receipts      = iota
 )
 
 func createHomeScreenModel() homeScreenModel {
 	return homeScreenModel{
-		choices:  []string{"Insert csv data", "Insert manual entry", "Update entry", "Delete entries"},
+		choices:  []string{"Insert csv data", "Insert manual entry", "Update entry", "Delete entries", "Reports", "Spending trends", "Compare periods", "Export entries", "Subscriptions", "Cash-flow forecast", "Saved searches", "Match receipts"},
 		selected: make(map[int]struct{}), // map of int to struct
 	}
 }
 
+// Only called for the model the program starts with, so recurring
+// entries are generated once per start. Storage is already prepared by
+// main.
 func (m homeScreenModel) Init() tea.Cmd {
-	return nil
+	return generateRecurringEntriesCmd
 }
 
 func (m homeScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
+	case recurringGeneratedMsg:
+		if msg.err != nil {
+			m = addHomeFeedback(m, "Error generating recurring entries: "+msg.err.Error())
+		} else if msg.generated > 0 {
+			m = addHomeFeedback(m, "Generated "+strconv.Itoa(msg.generated)+" recurring entries.")
+		}
+
 	// Is it a key press?
 	case tea.KeyMsg:
 
@@ -76,6 +96,25 @@ func (m homeScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					action_text: "delete",
 					next_model:  nil,
 				}), nil
+			case reports:
+				return createReportScreenModel(), nil
+			case trends:
+				return createTrendsScreenModel(), nil
+			case comparison:
+				return createComparisonScreenModel(), nil
+			case exportEntries:
+				return createFindEntryModel(action{
+					action_text: "export",
+					next_model:  nil,
+				}), nil
+			case subscriptions:
+				return createSubscriptionsScreenModel(), nil
+			case forecast:
+				return createForecastScreenModel(), nil
+			case savedSearches:
+				return createSavedSearchesScreenModel(), nil
+			case receipts:
+				return createReceiptsScreenModel(), nil
 			}
 
 			_, ok := m.selected[m.cursor]
@@ -118,7 +157,18 @@ func (m homeScreenModel) View() string {
 
 	// The footer
 	s += "\n\n" + textStyle.Width(HomeScreenWidth).PaddingLeft(2).Render("Press q to quit.") + "\n"
+	if m.feedback != "" {
+		s += inactiveStyle.PaddingLeft(2).PaddingRight(2).Render(m.feedback) + "\n"
+	}
 
 	// Send the UI for rendering
 	return s
 }
+
+func addHomeFeedback(m homeScreenModel, feedback string) homeScreenModel {
+	if m.feedback != "" {
+		m.feedback += "\n"
+	}
+	m.feedback += feedback
+	return m
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	preview_rows_view   = iota
+	preview_edit_view   = iota
+	preview_action_view = iota
+)
+
+// the category and tags are edited after the fields shown in the other tables
+const (
+	preview_category   = expense_credit + 1
+	preview_tags       = expense_credit + 2
+	preview_num_fields = expense_credit + 3
+)
+
+const CategoryWidth = 15
+
+// A parsed row waiting for confirmation
+type previewRow struct {
+	file_idx  int
+	entry_idx int
+	excluded  bool
+}
+
+// Shows parsed statements before anything is written to the database.
+// Rows can be edited, categorized or excluded; only confirming imports them.
+type importPreviewModel struct {
+	files         []fileImportResult
+	rows          []previewRow
+	active_view   int
+	page_idx      int
+	cursor        int
+	edit_field    int
+	edit_values   [preview_num_fields]string
+	feedback      string
+	feedback_fail bool
+}
+
+func createImportPreviewModel(files []fileImportResult) importPreviewModel {
+	m := importPreviewModel{
+		files:       files,
+		active_view: preview_rows_view,
+		feedback:    default_feedback,
+	}
+
+	for file_idx, file := range files {
+		for entry_idx := range file.Expenses {
+			m.rows = append(m.rows, previewRow{file_idx: file_idx, entry_idx: entry_idx})
+		}
+	}
+
+	return m
+}
+
+func (m importPreviewModel) Init() tea.Cmd {
+	return nil
+}
+
+// The expense under the cursor
+func (m importPreviewModel) selectedEntry() *Expense {
+	row := m.rows[m.page_idx*num_entries_per_page+m.cursor]
+	return &m.files[row.file_idx].Expenses[row.entry_idx]
+}
+
+func (m importPreviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+
+		if msg.String() == "ctrl+c" {
+			// nothing has been written, so cancelling just drops the rows
+			return createHomeScreenModel(), nil
+		}
+
+		switch m.active_view {
+		case preview_edit_view:
+			return m.updateEdit(msg)
+		case preview_action_view:
+			switch msg.String() {
+			case "tab", "esc":
+				m.active_view = preview_rows_view
+			case "enter":
+				return m.commit()
+			}
+			return m, nil
+		}
+
+		switch msg.String() {
+
+		case "up":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down":
+			num_entries_on_page := min(num_entries_per_page, len(m.rows)-(m.page_idx*num_entries_per_page))
+			if m.cursor < num_entries_on_page-1 {
+				m.cursor++
+			}
+
+		case "left":
+			if m.page_idx > 0 {
+				m.page_idx--
+				m.cursor = 0
+			}
+		case "right":
+			if (m.page_idx+1)*num_entries_per_page < len(m.rows) {
+				m.page_idx++
+				m.cursor = 0
+			}
+
+		case "tab":
+			m.active_view = preview_action_view
+
+		case "x":
+			if len(m.rows) > 0 {
+				row := &m.rows[m.page_idx*num_entries_per_page+m.cursor]
+				row.excluded = !row.excluded
+			}
+
+		case "e", "enter":
+			if len(m.rows) > 0 {
+				m = startPreviewEdit(m, expense_year)
+			}
+		case "c":
+			if len(m.rows) > 0 {
+				m = startPreviewEdit(m, preview_category)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+func startPreviewEdit(m importPreviewModel, field int) importPreviewModel {
+	entry := m.selectedEntry()
+	m.edit_values[expense_year] = strconv.Itoa(entry.Year)
+	m.edit_values[expense_month] = strconv.Itoa(entry.Month)
+	m.edit_values[expense_day] = strconv.Itoa(entry.Day)
+	m.edit_values[expense_description] = entry.Description
+	m.edit_values[expense_debit] = strconv.FormatFloat(entry.Debit, 'f', 2, 64)
+	m.edit_values[expense_credit] = strconv.FormatFloat(entry.Credit, 'f', 2, 64)
+	m.edit_values[preview_category] = entry.Category
+	m.edit_values[preview_tags] = strings.Join(entry.Tags, ", ")
+	m.edit_field = field
+	m.active_view = preview_edit_view
+	return m
+}
+
+func (m importPreviewModel) updateEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "tab", "down":
+		m.edit_field = (m.edit_field + 1) % preview_num_fields
+	case "shift+tab", "up":
+		m.edit_field = (m.edit_field + preview_num_fields - 1) % preview_num_fields
+	case "esc":
+		m.active_view = preview_rows_view
+		m.feedback = default_feedback
+		m.feedback_fail = false
+	case "backspace":
+		m.edit_values[m.edit_field] = removeLastChar(m.edit_values[m.edit_field])
+	case "enter":
+		entry, err := parsePreviewEdit(m.edit_values)
+		if err != nil {
+			m.feedback = err.Error()
+			m.feedback_fail = true
+			return m, nil
+		}
+
+		// fields that cannot be edited are kept as is
+		selected := m.selectedEntry()
+		entry.Account = selected.Account
+		entry.FITID = selected.FITID
+		entry.Total = selected.Total
+		checkValidEntryValues(&entry)
+		*selected = entry
+
+		m.active_view = preview_rows_view
+		m.feedback = default_feedback
+		m.feedback_fail = false
+	default:
+		m.edit_values[m.edit_field] += msg.String()
+	}
+	return m, nil
+}
+
+// Builds an expense from the edited fields. Empty amounts are zero.
+func parsePreviewEdit(values [preview_num_fields]string) (Expense, error) {
+	entry := Expense{
+		Description: strings.TrimSpace(values[expense_description]),
+		Category:    strings.TrimSpace(values[preview_category]),
+		Tags:        parseTags(values[preview_tags]),
+	}
+
+	year, err := strconv.Atoi(values[expense_year])
+	if err != nil {
+		return entry, errors.New("invalid year")
+	}
+	entry.Year = year
+
+	if month, err := time.Parse("Jan", values[expense_month]); err == nil {
+		entry.Month = int(month.Month())
+	} else if month, err := strconv.Atoi(values[expense_month]); err == nil && month >= 1 && month <= 12 {
+		entry.Month = month
+	} else {
+		return entry, errors.New("invalid month")
+	}
+
+	day, err := strconv.Atoi(values[expense_day])
+	if err != nil || !isValidDay(entry.Year, entry.Month, day) {
+		return entry, errors.New("invalid day, the month does not have it")
+	}
+	entry.Day = day
+
+	for _, field := range []int{expense_debit, expense_credit} {
+		if values[field] == "" {
+			continue
+		}
+		val, err := strconv.ParseFloat(values[field], 64)
+		if err != nil {
+			return entry, errors.New("invalid amount " + values[field])
+		}
+		if field == expense_debit {
+			entry.Debit = val
+		} else {
+			entry.Credit = val
+		}
+	}
+
+	return entry, nil
+}
+
+// Splits comma separated tags, dropping blanks and repeats
+func parseTags(text string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, tag := range strings.Split(text, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Imports the rows that were not excluded and shows the results
+func (m importPreviewModel) commit() (tea.Model, tea.Cmd) {
+	staged := make([]fileImportResult, len(m.files))
+	for idx, file := range m.files {
+		staged[idx] = file
+		staged[idx].Expenses = []Expense{}
+	}
+	for _, row := range m.rows {
+		if !row.excluded {
+			staged[row.file_idx].Expenses = append(staged[row.file_idx].Expenses, m.files[row.file_idx].Expenses[row.entry_idx])
+		}
+	}
+
+	results := commitStagedImports(staged)
+	post_insert_model := createPostInsertCSVScreenModel(importedExpenses(results))
+	post_insert_model.files = results
+	return post_insert_model, nil
+}
+
+func (m importPreviewModel) numIncludedRows() int {
+	included := 0
+	for _, row := range m.rows {
+		if !row.excluded && m.files[row.file_idx].Expenses[row.entry_idx].Valid {
+			included++
+		}
+	}
+	return included
+}
+
+func (m importPreviewModel) View() string {
+	s := selectedStyle.Width(HomeScreenWidth).Render("> Review import") + "\n"
+	s += renderPreviewFiles(m)
+	s += renderPreviewRows(m)
+	if m.active_view == preview_edit_view {
+		s += renderPreviewEdit(m)
+	}
+
+	s += "\n"
+	if m.feedback_fail {
+		s += errorStyle.Render(m.feedback) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
+
+	s += "\n" + textStyle.PaddingRight(2).Render("Import "+strconv.Itoa(m.numIncludedRows())+" entries?")
+	sym := ""
+	if m.active_view == preview_action_view {
+		sym = "Press enter to import [x]"
+	}
+	s += activeDeleteViewStyle(m.active_view, preview_action_view).Render(sym) + "\n"
+	s += textStyle.Render("Nothing is written until you confirm. Press Ctrl+C to cancel.") + "\n"
+	return s
+}
+
+// One line per file that will not be imported, e.g. already imported
+func renderPreviewFiles(m importPreviewModel) string {
+	s := ""
+	for _, file := range m.files {
+		switch {
+		case file.Error != "":
+			s += errorStyle.Render(filepath.Base(file.Filename)+": "+file.Error) + "\n"
+		case file.Skipped:
+			s += inactiveStyle.Render(filepath.Base(file.Filename)+": skipped - already imported") + "\n"
+		}
+	}
+	return s
+}
+
+func renderPreviewRows(m importPreviewModel) string {
+	s := "\n" + textStyle.Width((DateWidth+3)*3).Render("Parsed Entries")
+
+	if len(m.rows) > 0 {
+		page_str := "Entries: " +
+			strconv.Itoa(m.page_idx*num_entries_per_page+1) + "-" +
+			strconv.Itoa(min((m.page_idx+1)*num_entries_per_page, len(m.rows))) + " / " +
+			strconv.Itoa(len(m.rows))
+
+		s += textStyle.Width(DescriptionWidth + 3).Render(page_str)
+		s += textStyle.Width((DefaultWidth + 3) * 2).Render("Press < or > to switch pages")
+	}
+
+	s += "\n" + textStyle.Render("Press e to edit, c to categorize, x to exclude, tab to confirm.")
+	s += "\n"
+	s += textStyle.Width(DateWidth).Render("Year")
+	s += " | "
+	s += textStyle.Width(DateWidth).Render("Month")
+	s += " | "
+	s += textStyle.Width(DateWidth).Render("Day")
+	s += " | "
+	s += textStyle.Width(DescriptionWidth).Render("Description")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Debit")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Credit")
+	s += " | "
+	s += textStyle.Width(CategoryWidth).Render("Category")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Import")
+	s += "\n"
+
+	page_start := m.page_idx * num_entries_per_page
+	page_end := min(len(m.rows), page_start+num_entries_per_page)
+
+	for idx := page_start; idx < page_end; idx++ {
+		row := m.rows[idx]
+		entry := m.files[row.file_idx].Expenses[row.entry_idx]
+		style := selectPreviewRowStyle(m, idx-page_start, row, entry)
+
+		status := "[x]"
+		if row.excluded {
+			status = "[ ] excluded"
+		} else if !entry.Valid {
+			status = "invalid"
+		}
+
+		line := style.Width(DateWidth).Render(strconv.Itoa(entry.Year))
+		line += " | "
+		line += style.Width(DateWidth).Render(strconv.Itoa(entry.Month))
+		line += " | "
+		line += style.Width(DateWidth).Render(strconv.Itoa(entry.Day))
+		line += " | "
+		line += style.Width(DescriptionWidth).Render(truncate(entry.Description, DescriptionWidth))
+		line += " | "
+		line += style.Width(DefaultWidth).Render(strconv.FormatFloat(entry.Debit, 'f', 2, 64))
+		line += " | "
+		line += style.Width(DefaultWidth).Render(strconv.FormatFloat(entry.Credit, 'f', 2, 64))
+		line += " | "
+		line += style.Width(CategoryWidth).Render(truncate(entry.Category, CategoryWidth))
+		line += " | "
+		line += style.Width(DefaultWidth).Render(status)
+		s += line + "\n"
+	}
+
+	return s
+}
+
+func renderPreviewEdit(m importPreviewModel) string {
+	labels := [preview_num_fields]string{
+		expense_year:        "Year: ",
+		expense_month:       "Month: ",
+		expense_day:         "Day: ",
+		expense_description: "Description: ",
+		expense_debit:       "Debit: ",
+		expense_credit:      "Credit: ",
+		preview_category:    "Category: ",
+		preview_tags:        "Tags: ",
+	}
+
+	s := "\n" + textStyle.Render("Edit entry - tab to move between fields, enter to save, esc to cancel.") + "\n"
+	for field, label := range labels {
+		style := inactiveStyle
+		if field == m.edit_field {
+			style = selectedStyle
+		}
+		s += textStyle.PaddingLeft(2).Width(FindEntryLabelWidth).Render(label) +
+			style.PaddingLeft(2).PaddingRight(2).Render(m.edit_values[field]) + "\n"
+	}
+	return s
+}
+
+// highlights the row under the cursor, then invalid and excluded rows
+func selectPreviewRowStyle(m importPreviewModel, row int, preview_row previewRow, entry Expense) lipgloss.Style {
+	switch {
+	case m.active_view != preview_action_view && m.cursor == row:
+		return selectedStyle
+	case !entry.Valid:
+		return errorStyle
+	case preview_row.excluded:
+		return textStyle
+	default:
+		return inactiveStyle
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -13,15 +14,65 @@ import (
 )
 
 type insertCSVScreenModel struct {
-	filename string
+	filename   string
+	feedback   string
+	browse_dir string
+	listing    []browserEntry
+	cursor     int
 }
 
 const InsertScreenWidth = 20
+const BrowserNameWidth = 36
+const BrowserTimeWidth = 18
 
+// The file browser opens in the directory of the last import,
+// or the working directory the first time
 func createInsertCSVScreenModel() insertCSVScreenModel {
-	return insertCSVScreenModel{
+	m := insertCSVScreenModel{
 		filename: "",
 	}
+
+	dir := "."
+	if cfg, err := loadConfig(); err == nil && cfg.LastImportDir != "" {
+		if info, err := os.Stat(cfg.LastImportDir); err == nil && info.IsDir() {
+			dir = cfg.LastImportDir
+		}
+	}
+	if dir == "." {
+		if wd, err := os.Getwd(); err == nil {
+			dir = wd
+		}
+	}
+
+	return browseDir(m, dir)
+}
+
+func browseDir(m insertCSVScreenModel, dir string) insertCSVScreenModel {
+	listing, err := listStatementDir(dir)
+	if err != nil {
+		m.feedback = err.Error()
+		return m
+	}
+	m.browse_dir = dir
+	m.listing = listing
+	m.cursor = defaultBrowserRow(listing)
+	return m
+}
+
+// The first statement file, or the first directory other than the
+// parent, so enter never imports ".." by default
+func defaultBrowserRow(listing []browserEntry) int {
+	for idx, entry := range listing {
+		if !entry.is_dir {
+			return idx
+		}
+	}
+	for idx, entry := range listing {
+		if entry.name != ".." {
+			return idx
+		}
+	}
+	return 0
 }
 
 func (m insertCSVScreenModel) Init() tea.Cmd {
@@ -36,13 +87,30 @@ func (m insertCSVScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 
 		case "up":
-			// do nothing
+			if m.cursor > 0 {
+				m.cursor--
+				m.filename = m.listing[m.cursor].path
+			}
 		case "down":
-			// do nothing
+			if m.cursor < len(m.listing)-1 {
+				m.cursor++
+				m.filename = m.listing[m.cursor].path
+			}
 		case "left":
-			// do nothing
+			m = browseDir(m, filepath.Dir(m.browse_dir))
+			m.filename = ""
 		case "right":
-			// do nothing
+			if len(m.listing) > 0 && m.listing[m.cursor].is_dir {
+				m = browseDir(m, m.listing[m.cursor].path)
+				m.filename = ""
+			}
+
+		case "tab":
+			m.filename = completePath(m.filename, isSupportedStatementFile)
+			dir := expandHome(m.filename)
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				m = browseDir(m, filepath.Clean(dir))
+			}
 
 		case "ctrl+c":
 			return createHomeScreenModel(), nil
@@ -54,6 +122,16 @@ func (m insertCSVScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "enter":
+			// on a selected directory enter opens it like right does, a
+			// typed directory is imported
+			if len(m.listing) > 0 && (m.filename == "" || m.filename == m.listing[m.cursor].path) {
+				if m.listing[m.cursor].is_dir {
+					m = browseDir(m, m.listing[m.cursor].path)
+					m.filename = ""
+					return m, nil
+				}
+				m.filename = m.listing[m.cursor].path
+			}
 			return m.enterCSV()
 
 		default:
@@ -69,27 +147,76 @@ func (m insertCSVScreenModel) View() string {
 	s := selectedStyle.Width(HomeScreenWidth).Render("> Insert csv data") + "\n"
 	s += textStyle.Width(InsertScreenWidth).PaddingLeft(2).Render("Enter filename:")
 	s += errorStyle.PaddingLeft(2).PaddingRight(2).Render(m.filename)
-	s += "\n\n" + textStyle.Width(HomeScreenWidth).PaddingLeft(2).Render("Press Ctrl+C to go back to home screen.") + "\n"
+	s += "\n" + textStyle.PaddingLeft(2).Render("A file, a directory or a pattern like statements/*.csv")
+	if m.feedback != "" {
+		s += "\n" + errorStyle.PaddingLeft(2).PaddingRight(2).Render(m.feedback)
+	}
+	s += "\n\n" + renderFileBrowser(m)
+	s += "\n" + textStyle.Width(HomeScreenWidth).PaddingLeft(2).Render("Press Ctrl+C to go back to home screen.") + "\n"
+	return s
+}
+
+// Shows the page of the directory listing containing the cursor
+func renderFileBrowser(m insertCSVScreenModel) string {
+	s := textStyle.Width(BrowserNameWidth+BrowserTimeWidth+DefaultWidth+6).Render(m.browse_dir) + "\n"
+	s += textStyle.Render("Up/down to select, right or enter to open a directory, left for the parent, tab to complete.") + "\n"
+
+	page_start := (m.cursor / num_entries_per_page) * num_entries_per_page
+	page_end := min(len(m.listing), page_start+num_entries_per_page)
+
+	for idx := page_start; idx < page_end; idx++ {
+		entry := m.listing[idx]
+		style := inactiveStyle
+		if idx == m.cursor {
+			style = selectedStyle
+		}
+
+		name := entry.name
+		size := formatFileSize(entry.size)
+		modified := entry.mod_time.Format(browser_time_layout)
+		if entry.is_dir {
+			name += string(filepath.Separator)
+			size = "dir"
+		}
+		if entry.name == ".." {
+			modified = ""
+		}
+
+		line := style.Width(BrowserNameWidth).Render(truncate(name, BrowserNameWidth))
+		line += " | "
+		line += style.Width(DefaultWidth).Render(size)
+		line += " | "
+		line += style.Width(BrowserTimeWidth).Render(modified)
+		s += line + "\n"
+	}
+
+	if len(m.listing) > num_entries_per_page {
+		s += textStyle.Render(strconv.Itoa(page_start+1)+"-"+strconv.Itoa(page_end)+" / "+strconv.Itoa(len(m.listing))) + "\n"
+	}
 	return s
 }
 
 func (m insertCSVScreenModel) enterCSV() (tea.Model, tea.Cmd) {
-	data, err := readCSV(m.filename)
+	files, err := expandStatementPaths(m.filename)
 	if err != nil {
-		fmt.Println("Error reading file! ", err)
-		return m, tea.Quit
+		m.feedback = err.Error()
+		return m, nil
 	}
-	reader, err := createCSVReader(data)
-	if err != nil {
-		fmt.Println("Error creating CSV reader: ", err)
-		return m, tea.Quit
+
+	// not remembering the directory is no reason to stop the import
+	_ = rememberImportDir(filepath.Dir(files[0]))
+
+	// nothing is written until the user confirms on the preview screen
+	results := stageStatementFiles(files, defaultImportProfile(), false)
+	if len(importedExpenses(results)) == 0 {
+		insertingCsvScreenModel := createPostInsertCSVScreenModel(nil)
+		insertingCsvScreenModel.files = results
+		return insertingCsvScreenModel, nil
 	}
-	expenses_inserted := insertCSVIntoMongo(reader)
-	insertingCsvScreenModel := createPostInsertCSVScreenModel(expenses_inserted)
-	return insertingCsvScreenModel, nil
+	return createImportPreviewModel(results), nil
 }
 
-func readCSV(filename string) ([]byte, error) {
+func readStatementFile(filename string) ([]byte, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -107,47 +234,60 @@ func createCSVReader(data []byte) (*csv.Reader, error) {
 	return reader, nil
 }
 
-func insertCSVIntoMongo(reader *csv.Reader) []Expense {
+// Converts csv rows into expenses using the column layout of profile,
+// marking each one as valid or not. Nothing is written to the database.
+// A malformed file is an error rather than a partial import.
+func parseCSVEntries(reader *csv.Reader, profile importProfile) ([]Expense, error) {
 
 	entries := []Expense{}
 
+	// rows can have different numbers of columns, e.g. a trailing total
+	reader.FieldsPerRecord = -1
+
 	for {
 		record, err := reader.Read()
-		if err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				fmt.Println("Error reading CSV data: ", err)
-				break
-			}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("reading csv data: %w", err)
 		}
 
 		entry := Expense{}
 
+		// profile columns start at 1, 0 means the column is not in the file
 		for i, str := range record {
-			switch i {
-			case csv_date_col:
+			switch i + 1 {
+			case profile.DateColumn:
 				// parse date
-				layout := "01/02/2006"
-				parsedDate, err := time.Parse(layout, str)
+				parsedDate, err := time.Parse(profile.DateLayout, str)
 				if err == nil {
 					entry.Month = int(parsedDate.Month())
 					entry.Day = parsedDate.Day()
 					entry.Year = parsedDate.Year()
 				}
-			case csv_description_col:
+			case profile.DescriptionColumn:
 				entry.Description = str
-			case csv_debit_col:
+			case profile.DebitColumn:
 				val, err := strconv.ParseFloat(str, 64)
 				if err == nil {
 					entry.Debit = val
 				}
-			case csv_credit_col:
+			case profile.CreditColumn:
 				val, err := strconv.ParseFloat(str, 64)
 				if err == nil {
 					entry.Credit = val
 				}
-			case csv_total_col:
+			case profile.AmountColumn:
+				// single signed amount column, negative amounts are debits
+				val, err := strconv.ParseFloat(str, 64)
+				if err == nil {
+					if val < 0 {
+						entry.Debit = -val
+					} else {
+						entry.Credit = val
+					}
+				}
+			case profile.TotalColumn:
 				val, err := strconv.ParseFloat(str, 64)
 				if err == nil {
 					entry.Total = val
@@ -161,10 +301,5 @@ func insertCSVIntoMongo(reader *csv.Reader) []Expense {
 		entries = append(entries, entry)
 	}
 
-	// context.TODO() creates an empty context
-	// options.Client().ApplyURI() is part of mongo-driver/mongo/options package
-
-	mongoInsertEntries(entries)
-
-	return entries
+	return entries, nil
 }
@@ -360,7 +360,7 @@ func checkIfManualEntriesValid(m *manualInsertModel) []Expense {
 			case expense_day:
 				if m.entries[row].Day != "" {
 					day, err := strconv.Atoi(m.entries[row].Day)
-					if err == nil && day >= 1 && day <= 31 {
+					if err == nil && isValidDay(entry.Year, entry.Month, day) {
 						entry.Day = day
 						m.valid[row][col] = selected_style
 					} else {
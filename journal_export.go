@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	journal_ledger    = iota
+	journal_hledger   = iota
+	journal_beancount = iota
+)
+
+// Returns an export writer for a plain text accounting journal,
+// using the account mapping from the config file
+func journalWriter(dialect int) func(w io.Writer, expenses []Expense) error {
+	return func(w io.Writer, expenses []Expense) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		// no attachments directory only leaves out the attachment paths
+		dir, _ := attachmentsDir()
+		return writeJournal(w, expenses, cfg.Ledger, dialect, dir)
+	}
+}
+
+// The account the money went to (debits) or came from (credits)
+func categoryAccount(cfg ledgerConfig, entry Expense) string {
+	if account, ok := cfg.Categories[entry.Category]; ok && entry.Category != "" {
+		return account
+	}
+	if entry.Debit >= entry.Credit {
+		if entry.Category != "" {
+			return "Expenses:" + entry.Category
+		}
+		return cfg.ExpenseAccount
+	}
+	if entry.Category != "" {
+		return "Income:" + entry.Category
+	}
+	return cfg.IncomeAccount
+}
+
+// The bank or card account the expense was paid from
+func assetAccount(cfg ledgerConfig, entry Expense) string {
+	if account, ok := cfg.Accounts[entry.Account]; ok && entry.Account != "" {
+		return account
+	}
+	return cfg.AssetAccount
+}
+
+// Beancount account names are capitalized components of letters, digits
+// and dashes separated by colons
+func beancountAccountName(account string) string {
+	components := strings.Split(account, ":")
+	for i, component := range components {
+		cleaned := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+				return r
+			}
+			if unicode.IsSpace(r) {
+				return '-'
+			}
+			return -1
+		}, component)
+		cleaned = strings.Trim(cleaned, "-")
+		if cleaned == "" {
+			cleaned = "Unknown"
+		}
+		runes := []rune(cleaned)
+		runes[0] = unicode.ToUpper(runes[0])
+		if !unicode.IsLetter(runes[0]) {
+			runes = append([]rune("X"), runes...)
+		}
+		components[i] = string(runes)
+	}
+	return strings.Join(components, ":")
+}
+
+// Ledger separates the account from the amount with two or more spaces,
+// so account names must not contain them
+func ledgerAccountName(account string) string {
+	return strings.Join(strings.Fields(account), " ")
+}
+
+// Notes and the paths of stored attachments are written as comments, or
+// as metadata for beancount
+func writeJournal(w io.Writer, expenses []Expense, cfg ledgerConfig, dialect int, attachments_dir string) error {
+	sorted := make([]Expense, len(expenses))
+	copy(sorted, expenses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return dateKey(dateOf(sorted[i].Year, sorted[i].Month, sorted[i].Day)) <
+			dateKey(dateOf(sorted[j].Year, sorted[j].Month, sorted[j].Day))
+	})
+
+	account_name := ledgerAccountName
+	if dialect == journal_beancount {
+		account_name = beancountAccountName
+	}
+
+	if dialect == journal_beancount && len(sorted) > 0 {
+		if err := writeBeancountOpens(w, sorted, cfg); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range sorted {
+		date := dateOf(entry.Year, entry.Month, entry.Day)
+		amount := entry.Debit - entry.Credit
+		to := account_name(categoryAccount(cfg, entry))
+		from := account_name(assetAccount(cfg, entry))
+
+		notes := strings.ReplaceAll(entry.Notes, "\n", " ")
+		paths := attachmentPaths(attachments_dir, entry)
+
+		var err error
+		switch dialect {
+		case journal_beancount:
+			meta := ""
+			if notes != "" {
+				meta += fmt.Sprintf("  notes: %q\n", notes)
+			}
+			if len(paths) > 0 {
+				meta += fmt.Sprintf("  attachments: %q\n", strings.Join(paths, ";"))
+			}
+			_, err = fmt.Fprintf(w, "%s * %q\n%s  %s  %s %s\n  %s  %s %s\n\n",
+				date.Format(date_layout), entry.Description, meta,
+				to, formatAmount(amount), cfg.Currency,
+				from, formatAmount(-amount), cfg.Currency)
+		default:
+			layout := "2006/01/02"
+			if dialect == journal_hledger {
+				layout = date_layout
+			}
+			comments := ""
+			if notes != "" {
+				comments += "    ; " + notes + "\n"
+			}
+			for _, path := range paths {
+				comments += "    ; attachment: " + path + "\n"
+			}
+			_, err = fmt.Fprintf(w, "%s %s\n%s    %s    %s %s\n    %s\n\n",
+				date.Format(layout), strings.ReplaceAll(entry.Description, "\n", " "), comments,
+				to, formatAmount(amount), cfg.Currency,
+				from)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Beancount requires accounts to be opened before they are used
+func writeBeancountOpens(w io.Writer, sorted []Expense, cfg ledgerConfig) error {
+	opened := map[string]bool{}
+	accounts := []string{}
+	for _, entry := range sorted {
+		for _, account := range []string{categoryAccount(cfg, entry), assetAccount(cfg, entry)} {
+			name := beancountAccountName(account)
+			if !opened[name] {
+				opened[name] = true
+				accounts = append(accounts, name)
+			}
+		}
+	}
+	sort.Strings(accounts)
+
+	first := sorted[0]
+	open_date := dateOf(first.Year, first.Month, first.Day).Format(date_layout)
+	for _, account := range accounts {
+		if _, err := fmt.Fprintf(w, "%s open %s\n", open_date, account); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,15 +14,22 @@ import (
 // Fields have to start with capital letter or else they
 // will not be properly entered into MongoDB!
 type Expense struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty"`
-	Month       int                `bson:"month"`
-	Day         int                `bson:"day"`
-	Year        int                `bson:"year"`
-	Description string             `bson:"description"`
-	Debit       float64            `bson:"debit"`
-	Credit      float64            `bson:"credit"`
-	Total       float64            `bson:"total,omitempty"`
-	Valid       bool               `bson:"valid,omitempty"`
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Month       int                `bson:"month" json:"month"`
+	Day         int                `bson:"day" json:"day"`
+	Year        int                `bson:"year" json:"year"`
+	Date        time.Time          `bson:"date" json:"-"` // set from year, month and day when stored
+	Description string             `bson:"description" json:"description"`
+	Debit       float64            `bson:"debit" json:"debit"`
+	Credit      float64            `bson:"credit" json:"credit"`
+	Category    string             `bson:"category,omitempty" json:"category,omitempty"`
+	Account     string             `bson:"account,omitempty" json:"account,omitempty"`
+	FITID       string             `bson:"fitid,omitempty" json:"fitid,omitempty"` // bank transaction ID from OFX/QFX statements
+	Tags        []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	Notes       string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	Attachments []attachment       `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	Total       float64            `bson:"total,omitempty" json:"total,omitempty"`
+	Valid       bool               `bson:"valid,omitempty" json:"valid"`
 }
 
 // could use reflection, but mapping struct fields to index is clearer
@@ -52,6 +60,8 @@ func checkValidEntryValues(entry *Expense) {
 		return
 	} else if entry.Year == 0 {
 		return
+	} else if !isValidDay(entry.Year, entry.Month, entry.Day) {
+		return
 	} else if entry.Description == "" {
 		return
 	} else if entry.Debit == 0 && entry.Credit == 0 {
@@ -61,6 +71,18 @@ func checkValidEntryValues(entry *Expense) {
 	entry.Valid = true
 }
 
+// Whether day exists in the month, e.g. not 31 Feb. Without a year and
+// month only 1 to 31 are checked.
+func isValidDay(year int, month int, day int) bool {
+	if day < 1 || day > 31 {
+		return false
+	}
+	if year == 0 || month < 1 || month > 12 {
+		return true
+	}
+	return dateOf(year, month, day).Day() == day
+}
+
 func mongoInsertEntries(entries []Expense) {
 	ctx := context.TODO()
 
@@ -79,12 +101,20 @@ func mongoInsertEntries(entries []Expense) {
 
 	for _, entry := range entries {
 		if entry.Valid {
+			entry.Date = dateOf(entry.Year, entry.Month, entry.Day)
 			coll.InsertOne(ctx, entry)
 		}
 	}
 }
 
-func mongoFindMatchingEntries(entry Expense) []Expense {
+// A search entry that matches every expense
+func matchAllEntries() Expense {
+	return Expense{Year: invalid, Month: invalid, Day: invalid, Debit: invalid, Credit: invalid}
+}
+
+// Builds the query used to search for expenses. Fields set to invalid
+// (or an empty description) are not used as search criteria.
+func expenseSearchFilter(entry Expense) bson.D {
 	filters := bson.A{}
 
 	if entry.Year != invalid {
@@ -97,11 +127,7 @@ func mongoFindMatchingEntries(entry Expense) []Expense {
 		filters = append(filters, bson.M{"day": entry.Day})
 	}
 	if entry.Description != "" {
-		filters = append(filters, bson.M{
-			"description": bson.M{
-				"$regex":   ".*" + entry.Description + ".*", // Matches any string containing entry description
-				"$options": "i",                             // Case-insensitive search
-			}})
+		filters = append(filters, descriptionFilter(entry.Description))
 	}
 	if entry.Debit != invalid {
 		filters = append(filters, bson.M{"debit": entry.Debit})
@@ -112,36 +138,132 @@ func mongoFindMatchingEntries(entry Expense) []Expense {
 
 	filter := bson.D{} // bson.D is a list
 	if len(filters) > 0 {
-		filter = bson.D{{"$and", filters}}
+		filter = bson.D{{Key: "$and", Value: filters}}
 	}
 
-	ctx := context.TODO()
+	return filter
+}
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoUri))
-	if err != nil {
-		panic(err)
+// What to search for: the fields of entry that are not invalid, dates
+// within date_range, amounts within the ranges and, if set, a parsed query
+type entrySearch struct {
+	entry        Expense
+	date_range   period
+	debit_range  amountRange
+	credit_range amountRange
+	amount_range amountRange // either the debit or the credit
+	desc_regex   string      // regular expression the description matches
+	query        queryNode
+	sort         entrySort
+}
+
+const (
+	sort_date        = iota
+	sort_description = iota
+	sort_debit       = iota
+	sort_credit      = iota
+	sort_category    = iota
+	sort_relevance   = iota // text: query terms only
+	num_sort_columns = iota
+)
+
+var sort_column_names = [num_sort_columns]string{
+	sort_date:        "date",
+	sort_description: "description",
+	sort_debit:       "debit",
+	sort_credit:      "credit",
+	sort_category:    "category",
+	sort_relevance:   "relevance",
+}
+
+// Order of search results, oldest first by default
+type entrySort struct {
+	column     int
+	descending bool
+}
+
+func (s entrySort) String() string {
+	if s.column == sort_relevance {
+		return "relevance"
+	}
+	direction := "ascending"
+	if s.descending {
+		direction = "descending"
 	}
+	return sort_column_names[s.column] + ", " + direction
+}
 
-	defer func() {
-		if err = client.Disconnect(ctx); err != nil {
-			panic(err)
-		}
-	}()
+// The _id breaks ties so pages never overlap. Relevance, which only a
+// text search has, falls back to date order without one.
+func (s entrySearch) sortKeys() bson.D {
+	order := 1
+	if s.sort.descending {
+		order = -1
+	}
+	switch {
+	case s.sort.column != sort_relevance:
+		return bson.D{{Key: sort_column_names[s.sort.column], Value: order}, {Key: "_id", Value: order}}
+	case hasTextTerm(s.query):
+		return bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: 1}}
+	}
+	return bson.D{{Key: "date", Value: order}, {Key: "_id", Value: order}}
+}
 
-	coll := client.Database(MongoDb).Collection(MongoCollection)
+// Amounts between min and max (inclusive). An unset end is left open.
+type amountRange struct {
+	min     float64
+	max     float64
+	has_min bool
+	has_max bool
+}
 
-	search_cursor, err := coll.Find(ctx, filter)
-	if err != nil {
-		log.Fatal(err)
+func (r amountRange) isSet() bool {
+	return r.has_min || r.has_max
+}
+
+func (r amountRange) condition() bson.M {
+	condition := bson.M{}
+	if r.has_min {
+		condition["$gte"] = r.min
+	}
+	if r.has_max {
+		condition["$lte"] = r.max
 	}
-	defer search_cursor.Close(ctx)
+	return condition
+}
 
-	var expenses []Expense
-	if err = search_cursor.All(ctx, &expenses); err != nil {
-		panic(err)
+// Matches entries whose debit or credit meets condition. A zero amount
+// means the entry has nothing on that side, so it never matches.
+func eitherAmountFilter(condition bson.M) bson.M {
+	sides := bson.A{}
+	for _, field := range []string{"debit", "credit"} {
+		side := bson.M{"$ne": 0.0}
+		for op, value := range condition {
+			side[op] = value
+		}
+		sides = append(sides, bson.M{field: side})
 	}
+	return bson.M{"$or": sides}
+}
 
-	return expenses
+func (s entrySearch) filter() bson.M {
+	filters := bson.A{expenseSearchFilter(s.entry), dateRangeFilter(s.date_range)}
+	if s.debit_range.isSet() {
+		filters = append(filters, bson.M{"debit": s.debit_range.condition()})
+	}
+	if s.credit_range.isSet() {
+		filters = append(filters, bson.M{"credit": s.credit_range.condition()})
+	}
+	if s.amount_range.isSet() {
+		filters = append(filters, eitherAmountFilter(s.amount_range.condition()))
+	}
+	if s.desc_regex != "" {
+		filters = append(filters, bson.M{"description": regexFilter(s.desc_regex)})
+	}
+	if s.query != nil {
+		filters = append(filters, s.query.filter())
+	}
+	return bson.M{"$and": filters}
 }
 
 func mongoUpdateEntries(old_entries []Expense, new_entries []Expense) {
@@ -167,16 +289,8 @@ func mongoUpdateEntry(old_entry Expense, new_entry Expense) {
 
 	coll := client.Database(MongoDb).Collection(MongoCollection)
 
-	filter := bson.D{{"_id", old_entry.ID}}
-	update := bson.D{{"$set",
-		bson.D{
-			{"year", new_entry.Year},
-			{"month", new_entry.Month},
-			{"day", new_entry.Day},
-			{"description", new_entry.Description},
-			{"debit", new_entry.Debit},
-			{"credit", new_entry.Credit},
-		}}}
+	filter := bson.M{"_id": old_entry.ID}
+	update := expenseUpdate(new_entry)
 
 	_, err = coll.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -184,6 +298,24 @@ func mongoUpdateEntry(old_entry Expense, new_entry Expense) {
 	}
 }
 
+// The fields a user is allowed to change on an existing expense.
+// Tags are left alone unless set, an empty list removes them.
+func expenseUpdate(entry Expense) bson.M {
+	fields := bson.M{
+		"year":        entry.Year,
+		"month":       entry.Month,
+		"day":         entry.Day,
+		"date":        dateOf(entry.Year, entry.Month, entry.Day),
+		"description": entry.Description,
+		"debit":       entry.Debit,
+		"credit":      entry.Credit,
+	}
+	if entry.Tags != nil {
+		fields["tags"] = entry.Tags
+	}
+	return bson.M{"$set": fields}
+}
+
 func mongoDeleteEntries(entries []Expense) {
 	for _, entry := range entries {
 		mongoDeleteEntry(entry)
@@ -215,3 +347,319 @@ func mongoDeleteEntry(entry Expense) {
 		log.Fatalf("Error deleting document: %v", err)
 	}
 }
+
+// The functions below return errors instead of panicking so that
+// long running callers (the REST API) can report failures and keep going.
+
+func withCollection(name string, fn func(ctx context.Context, coll *mongo.Collection) error) error {
+	ctx := context.TODO()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoUri))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(ctx)
+
+	return fn(ctx, client.Database(MongoDb).Collection(name))
+}
+
+func withExpensesCollection(fn func(ctx context.Context, coll *mongo.Collection) error) error {
+	return withCollection(MongoCollection, fn)
+}
+
+func mongoFindEntriesPage(search entrySearch, skip int64, limit int64) ([]Expense, error) {
+	expenses := []Expense{}
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		opts := options.Find().SetSort(search.sortKeys()).SetSkip(skip).SetLimit(limit)
+		search_cursor, err := coll.Find(ctx, search.filter(), opts)
+		if err != nil {
+			return err
+		}
+		defer search_cursor.Close(ctx)
+
+		return search_cursor.All(ctx, &expenses)
+	})
+
+	return expenses, err
+}
+
+// Inserts the valid entries and returns how many were inserted
+func mongoInsertValidEntries(entries []Expense) (int, error) {
+	inserted := 0
+
+	if _, err := mongoMarkDuplicateEntries(entries); err != nil {
+		return inserted, err
+	}
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		for _, entry := range entries {
+			if !entry.Valid {
+				continue
+			}
+			entry.Date = dateOf(entry.Year, entry.Month, entry.Day)
+			if _, err := coll.InsertOne(ctx, entry); err != nil {
+				return err
+			}
+			inserted++
+		}
+		return nil
+	})
+
+	return inserted, err
+}
+
+func mongoFindEntries(search entrySearch) ([]Expense, error) {
+	expenses := []Expense{}
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		opts := options.Find().SetSort(search.sortKeys())
+		search_cursor, err := coll.Find(ctx, search.filter(), opts)
+		if err != nil {
+			return err
+		}
+		defer search_cursor.Close(ctx)
+
+		return search_cursor.All(ctx, &expenses)
+	})
+
+	return expenses, err
+}
+
+func withoutAttachmentsFilter(search entrySearch) bson.M {
+	return bson.M{"$and": bson.A{search.filter(), bson.M{"attachments.0": bson.M{"$exists": false}}}}
+}
+
+// At most limit entries of search without attachments, all of them if
+// limit is 0
+func mongoFindEntriesWithoutAttachments(search entrySearch, limit int64) ([]Expense, error) {
+	expenses := []Expense{}
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		opts := options.Find().SetSort(search.sortKeys()).SetLimit(limit)
+		search_cursor, err := coll.Find(ctx, withoutAttachmentsFilter(search), opts)
+		if err != nil {
+			return err
+		}
+		defer search_cursor.Close(ctx)
+
+		return search_cursor.All(ctx, &expenses)
+	})
+
+	return expenses, err
+}
+
+func mongoCountEntriesWithoutAttachments(search entrySearch) (int64, error) {
+	var count int64
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		var err error
+		count, err = coll.CountDocuments(ctx, withoutAttachmentsFilter(search))
+		return err
+	})
+
+	return count, err
+}
+
+// Marks entries whose bank transaction ID (FITID) is already stored for
+// the same account, or repeated earlier in entries, as invalid. Returns
+// how many valid entries were marked.
+func mongoMarkDuplicateEntries(entries []Expense) (int, error) {
+	fitids := bson.A{}
+	for _, entry := range entries {
+		if entry.FITID != "" {
+			fitids = append(fitids, entry.FITID)
+		}
+	}
+	if len(fitids) == 0 {
+		return 0, nil
+	}
+
+	seen := map[string]bool{}
+	key := func(account string, fitid string) string {
+		return account + "\x00" + fitid
+	}
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		opts := options.Find().SetProjection(bson.M{"account": 1, "fitid": 1})
+		search_cursor, err := coll.Find(ctx, bson.M{"fitid": bson.M{"$in": fitids}}, opts)
+		if err != nil {
+			return err
+		}
+		defer search_cursor.Close(ctx)
+
+		var existing []Expense
+		if err = search_cursor.All(ctx, &existing); err != nil {
+			return err
+		}
+		for _, entry := range existing {
+			seen[key(entry.Account, entry.FITID)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	for idx := range entries {
+		if entries[idx].FITID == "" {
+			continue
+		}
+		k := key(entries[idx].Account, entries[idx].FITID)
+		if seen[k] && entries[idx].Valid {
+			entries[idx].Valid = false
+			marked++
+		}
+		seen[k] = true
+	}
+
+	return marked, nil
+}
+
+func mongoCountMatchingEntries(search entrySearch) (int64, error) {
+	var count int64
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		var err error
+		count, err = coll.CountDocuments(ctx, search.filter())
+		return err
+	})
+
+	return count, err
+}
+
+// Returns mongo.ErrNoDocuments if there is no expense with the given ID
+func mongoFindEntryByID(id primitive.ObjectID) (Expense, error) {
+	var expense Expense
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		return coll.FindOne(ctx, bson.M{"_id": id}).Decode(&expense)
+	})
+
+	return expense, err
+}
+
+func mongoInsertEntry(entry Expense) (primitive.ObjectID, error) {
+	var id primitive.ObjectID
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		entry.ID = primitive.NilObjectID
+		entry.Date = dateOf(entry.Year, entry.Month, entry.Day)
+		result, err := coll.InsertOne(ctx, entry)
+		if err != nil {
+			return err
+		}
+		id = result.InsertedID.(primitive.ObjectID)
+		return nil
+	})
+
+	return id, err
+}
+
+// Replaces every field a client may set, unlike expenseUpdate, so empty
+// category, account and notes are removed. Returns false if no expense
+// has the given ID.
+func mongoUpdateEntryByID(id primitive.ObjectID, entry Expense) (bool, error) {
+	found := false
+
+	update := expenseUpdate(entry)
+	unset := bson.M{}
+	for field, value := range map[string]string{"category": entry.Category, "account": entry.Account, "notes": entry.Notes} {
+		if value == "" {
+			unset[field] = ""
+		} else {
+			update["$set"].(bson.M)[field] = value
+		}
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, update)
+		if err != nil {
+			return err
+		}
+		found = result.MatchedCount > 0
+		return nil
+	})
+
+	return found, err
+}
+
+// Returns false if no expense has the given ID. Empty notes are removed.
+func mongoSetEntryNotes(id primitive.ObjectID, notes string) (bool, error) {
+	update := bson.M{"$set": bson.M{"notes": notes}}
+	if notes == "" {
+		update = bson.M{"$unset": bson.M{"notes": ""}}
+	}
+	return mongoUpdateEntryFields(id, update)
+}
+
+// Returns false if no expense has the given ID. Attachments with the hash
+// of one the entry already has are not added again, even under another
+// name.
+func mongoAddEntryAttachments(id primitive.ObjectID, attachments []attachment) (bool, error) {
+	found := false
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		for _, a := range attachments {
+			filter := bson.M{"_id": id, "attachments.hash": bson.M{"$ne": a.Hash}}
+			result, err := coll.UpdateOne(ctx, filter, bson.M{"$push": bson.M{"attachments": a}})
+			if err != nil {
+				return err
+			}
+			found = found || result.MatchedCount > 0
+		}
+		if found {
+			return nil
+		}
+
+		// nothing was added, either every file is attached already or
+		// there is no such expense
+		count, err := coll.CountDocuments(ctx, bson.M{"_id": id})
+		found = count > 0
+		return err
+	})
+
+	return found, err
+}
+
+// Removes the attachments with the given name or hash. Returns false if
+// no expense has the given ID.
+func mongoRemoveEntryAttachment(id primitive.ObjectID, name_or_hash string) (bool, error) {
+	match := bson.M{"$or": bson.A{bson.M{"name": name_or_hash}, bson.M{"hash": name_or_hash}}}
+	return mongoUpdateEntryFields(id, bson.M{"$pull": bson.M{"attachments": match}})
+}
+
+func mongoUpdateEntryFields(id primitive.ObjectID, update bson.M) (bool, error) {
+	found := false
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		result, err := coll.UpdateOne(ctx, bson.M{"_id": id}, update)
+		if err != nil {
+			return err
+		}
+		found = result.MatchedCount > 0
+		return nil
+	})
+
+	return found, err
+}
+
+// Returns false if no expense has the given ID
+func mongoDeleteEntryByID(id primitive.ObjectID) (bool, error) {
+	found := false
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		result, err := coll.DeleteOne(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		found = result.DeletedCount > 0
+		return nil
+	})
+
+	return found, err
+}
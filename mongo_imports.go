@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type importedFile struct {
+	Checksum   string    `bson:"_id"`
+	Filename   string    `bson:"filename"`
+	ImportedAt time.Time `bson:"imported_at"`
+	Inserted   int       `bson:"inserted"`
+}
+
+func mongoIsFileImported(checksum string) (bool, error) {
+	var count int64
+
+	err := withCollection(MongoImportsCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		var err error
+		count, err = coll.CountDocuments(ctx, bson.M{"_id": checksum})
+		return err
+	})
+
+	return count > 0, err
+}
+
+func mongoRecordImportedFile(checksum string, filename string, inserted int) error {
+	return withCollection(MongoImportsCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		file := importedFile{
+			Checksum:   checksum,
+			Filename:   filename,
+			ImportedAt: time.Now(),
+			Inserted:   inserted,
+		}
+		_, err := coll.ReplaceOne(ctx, bson.M{"_id": checksum}, file, options.Replace().SetUpsert(true))
+		return err
+	})
+}
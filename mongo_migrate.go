@@ -0,0 +1,208 @@
+// Expenses stored before the date field was added only have year, month
+// and day. Migrating sets their date and creates the indexes searches
+// rely on, including the text index of text: query terms. The schema
+// version it brought storage to is recorded, so it runs once per version
+// rather than at every start.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Bumped whenever migrateStorage has more to do, e.g. a new index
+const storage_schema_version = 1
+
+// The one document of MongoSchemaCollection
+type storageSchema struct {
+	ID           string    `bson:"_id"`
+	Version      int       `bson:"version"`
+	InvalidDates int       `bson:"invalid_dates"` // found by the last migration
+	MigratedAt   time.Time `bson:"migrated_at"`
+}
+
+const storage_schema_id = "expenses"
+
+type migrationResult struct {
+	Migrated int64 `json:"migrated"`
+
+	// stored with a day the month does not have, e.g. 31 Feb
+	InvalidDates []Expense `json:"invalid_dates"`
+}
+
+func mongoSetMissingEntryDates() (int64, error) {
+	var migrated int64
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		set_date := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"date": bson.M{"$dateFromParts": bson.M{"year": "$year", "month": "$month", "day": "$day"}},
+		}}}}
+		result, err := coll.UpdateMany(ctx, bson.M{"date": bson.M{"$exists": false}}, set_date)
+		if err != nil {
+			return err
+		}
+		migrated = result.ModifiedCount
+		return nil
+	})
+
+	return migrated, err
+}
+
+// Fields of the text index searched by text: query terms, by weight
+var entry_text_index_weights = bson.D{
+	{Key: "description", Value: 3},
+	{Key: "notes", Value: 2},
+	{Key: "category", Value: 1},
+	{Key: "tags", Value: 1},
+}
+
+const entry_text_index_name = "entries_text"
+
+// Index errors when an index of the same name exists with other fields
+const (
+	mongo_index_options_conflict   = 85
+	mongo_index_key_specs_conflict = 86
+)
+
+func mongoEnsureEntryIndexes() error {
+	text_keys := bson.D{}
+	for _, weight := range entry_text_index_weights {
+		text_keys = append(text_keys, bson.E{Key: weight.Key, Value: "text"})
+	}
+	text_index := mongo.IndexModel{
+		Keys:    text_keys,
+		Options: options.Index().SetName(entry_text_index_name).SetWeights(entry_text_index_weights),
+	}
+
+	return withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "date", Value: 1}}},
+			{Keys: bson.D{{Key: "year", Value: 1}, {Key: "month", Value: 1}}},
+		})
+		if err != nil {
+			return err
+		}
+
+		// a text index made by an older version lacks fields added since,
+		// and a collection can only have one
+		_, err = coll.Indexes().CreateOne(ctx, text_index)
+		var command_err mongo.CommandError
+		if errors.As(err, &command_err) &&
+			(command_err.Code == mongo_index_options_conflict || command_err.Code == mongo_index_key_specs_conflict) {
+			if _, err := coll.Indexes().DropOne(ctx, entry_text_index_name); err != nil {
+				return err
+			}
+			_, err = coll.Indexes().CreateOne(ctx, text_index)
+		}
+		return err
+	})
+}
+
+// Entries whose date rolled over into the next month because their day
+// does not exist, e.g. 31 Feb stored as 3 Mar
+func mongoFindInvalidEntryDates() ([]Expense, error) {
+	expenses := []Expense{}
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		filter := bson.M{"$expr": bson.M{"$ne": bson.A{bson.M{"$dayOfMonth": "$date"}, "$day"}}}
+		search_cursor, err := coll.Find(ctx, filter)
+		if err != nil {
+			return err
+		}
+		defer search_cursor.Close(ctx)
+
+		return search_cursor.All(ctx, &expenses)
+	})
+
+	return expenses, err
+}
+
+// A zero schema if storage was never migrated
+func mongoFindStorageSchema() (storageSchema, error) {
+	schema := storageSchema{}
+
+	err := withCollection(MongoSchemaCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		err := coll.FindOne(ctx, bson.M{"_id": storage_schema_id}).Decode(&schema)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	})
+
+	return schema, err
+}
+
+func mongoSaveStorageSchema(schema storageSchema) error {
+	schema.ID = storage_schema_id
+	return withCollection(MongoSchemaCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		_, err := coll.ReplaceOne(ctx, bson.M{"_id": storage_schema_id}, schema, options.Replace().SetUpsert(true))
+		return err
+	})
+}
+
+// Migrates storage unless it is at storage_schema_version already, which
+// costs one lookup. Run before any command or screen reads storage, since
+// date searches and text: terms rely on the migration. Returns how many
+// entries have dates that do not exist, as of the last migration.
+func prepareStorage() (int, error) {
+	schema, err := mongoFindStorageSchema()
+	if err != nil {
+		return 0, err
+	}
+	if schema.Version >= storage_schema_version {
+		return schema.InvalidDates, nil
+	}
+
+	result, err := migrateStorage()
+	return len(result.InvalidDates), err
+}
+
+// Sets missing dates, creates the indexes and lists entries with dates
+// that do not exist, which takes a scan of every entry. Records the
+// schema version once done.
+func migrateStorage() (migrationResult, error) {
+	result := migrationResult{}
+
+	migrated, err := mongoSetMissingEntryDates()
+	if err != nil {
+		return result, err
+	}
+	result.Migrated = migrated
+
+	if err := mongoEnsureEntryIndexes(); err != nil {
+		return result, err
+	}
+
+	result.InvalidDates, err = mongoFindInvalidEntryDates()
+	if err != nil {
+		return result, err
+	}
+
+	return result, mongoSaveStorageSchema(storageSchema{
+		Version:      storage_schema_version,
+		InvalidDates: len(result.InvalidDates),
+		MigratedAt:   time.Now(),
+	})
+}
+
+// `budgie migrate` sets the date of entries stored by older versions and
+// lists entries with dates that do not exist
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	result, err := migrateStorage()
+	if err != nil {
+		return err
+	}
+	return writeJSONOutput(result)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// An expense that repeats on a schedule, e.g. rent paid in cash
+type recurringTemplate struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Description string             `bson:"description" json:"description"`
+	Debit       float64            `bson:"debit" json:"debit"`
+	Credit      float64            `bson:"credit" json:"credit"`
+	Category    string             `bson:"category,omitempty" json:"category,omitempty"`
+	Account     string             `bson:"account,omitempty" json:"account,omitempty"`
+	Schedule    string             `bson:"schedule" json:"schedule"`     // e.g. "1st of every month"
+	StartDate   string             `bson:"start_date" json:"start_date"` // YYYY-MM-DD, first day entries can be due
+
+	// last due date entries were generated for, empty if none were yet
+	GeneratedUntil string `bson:"generated_until,omitempty" json:"generated_until,omitempty"`
+}
+
+func mongoFindRecurringTemplates() ([]recurringTemplate, error) {
+	templates := []recurringTemplate{}
+
+	err := withCollection(MongoRecurringCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		search_cursor, err := coll.Find(ctx, bson.M{})
+		if err != nil {
+			return err
+		}
+		defer search_cursor.Close(ctx)
+
+		return search_cursor.All(ctx, &templates)
+	})
+
+	return templates, err
+}
+
+func mongoInsertRecurringTemplate(template recurringTemplate) (primitive.ObjectID, error) {
+	var id primitive.ObjectID
+
+	err := withCollection(MongoRecurringCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		result, err := coll.InsertOne(ctx, template)
+		if err != nil {
+			return err
+		}
+		id = result.InsertedID.(primitive.ObjectID)
+		return nil
+	})
+
+	return id, err
+}
+
+// Returns false if there is no template with the ID
+func mongoDeleteRecurringTemplate(id primitive.ObjectID) (bool, error) {
+	found := false
+
+	err := withCollection(MongoRecurringCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		result, err := coll.DeleteOne(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		found = result.DeletedCount > 0
+		return nil
+	})
+
+	return found, err
+}
+
+func mongoSetRecurringGeneratedUntil(id primitive.ObjectID, date string) error {
+	return withCollection(MongoRecurringCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		_, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"generated_until": date}})
+		return err
+	})
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const num_top_payees = 5
+
+type payeeTotal struct {
+	Description string  `bson:"_id" json:"description"`
+	Total       float64 `bson:"total" json:"total"`
+	Count       int     `bson:"count" json:"count"`
+}
+
+type dayTotal struct {
+	Day   int     `bson:"_id" json:"day"`
+	Debit float64 `bson:"debit" json:"debit"`
+}
+
+type monthlySummary struct {
+	Year        int          `json:"year"`
+	Month       int          `json:"month"`
+	TotalDebit  float64      `json:"total_debit"`
+	TotalCredit float64      `json:"total_credit"`
+	Count       int          `json:"count"`
+	TopPayees   []payeeTotal `json:"top_payees"`
+	DailyDebits []dayTotal   `json:"daily_debits"`
+}
+
+func (s monthlySummary) Net() float64 {
+	return s.TotalCredit - s.TotalDebit
+}
+
+// Totals, top payees and per-day spending for one month, computed by the
+// database so only the aggregates are sent back.
+func mongoMonthlySummary(year int, month int) (monthlySummary, error) {
+	summary := monthlySummary{Year: year, Month: month}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"year": year, "month": month}}},
+		{{Key: "$facet", Value: bson.M{
+			"totals": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":    nil,
+					"debit":  bson.M{"$sum": "$debit"},
+					"credit": bson.M{"$sum": "$credit"},
+					"count":  bson.M{"$sum": 1},
+				}},
+			},
+			"payees": bson.A{
+				bson.M{"$match": bson.M{"debit": bson.M{"$gt": 0}}},
+				bson.M{"$group": bson.M{
+					"_id":   "$description",
+					"total": bson.M{"$sum": "$debit"},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$sort": bson.D{{Key: "total", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": num_top_payees},
+			},
+			"days": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":   "$day",
+					"debit": bson.M{"$sum": "$debit"},
+				}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+		}}},
+	}
+
+	var results []struct {
+		Totals []struct {
+			Debit  float64 `bson:"debit"`
+			Credit float64 `bson:"credit"`
+			Count  int     `bson:"count"`
+		} `bson:"totals"`
+		Payees []payeeTotal `bson:"payees"`
+		Days   []dayTotal   `bson:"days"`
+	}
+
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		cursor, err := coll.Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		return cursor.All(ctx, &results)
+	})
+	if err != nil {
+		return summary, err
+	}
+
+	if len(results) > 0 {
+		if len(results[0].Totals) > 0 {
+			summary.TotalDebit = results[0].Totals[0].Debit
+			summary.TotalCredit = results[0].Totals[0].Credit
+			summary.Count = results[0].Totals[0].Count
+		}
+		summary.TopPayees = results[0].Payees
+		summary.DailyDebits = results[0].Days
+	}
+
+	return summary, nil
+}
+
+const uncategorized = "Uncategorized"
+
+type monthTotal struct {
+	Year   int     `bson:"year"`
+	Month  int     `bson:"month"`
+	Debit  float64 `bson:"debit"`
+	Credit float64 `bson:"credit"`
+}
+
+type categoryTotal struct {
+	Category string  `bson:"_id"`
+	Debit    float64 `bson:"debit"`
+	Credit   float64 `bson:"credit"`
+}
+
+// Sorts year and month together, e.g. 202407
+func yearMonthKey(year int, month int) int {
+	return year*100 + month
+}
+
+// Debit and credit totals for num_months months ending at year/month.
+// Months without entries are included with zero totals.
+func mongoMonthlyTotals(year int, month int, num_months int) ([]monthTotal, error) {
+	start_year, start_month := addMonths(year, month, -(num_months - 1))
+
+	year_month := bson.M{"$add": bson.A{bson.M{"$multiply": bson.A{"$year", 100}}, "$month"}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$expr": bson.M{"$and": bson.A{
+			bson.M{"$gte": bson.A{year_month, yearMonthKey(start_year, start_month)}},
+			bson.M{"$lte": bson.A{year_month, yearMonthKey(year, month)}},
+		}}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"year": "$year", "month": "$month"},
+			"debit":  bson.M{"$sum": "$debit"},
+			"credit": bson.M{"$sum": "$credit"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"year":   "$_id.year",
+			"month":  "$_id.month",
+			"debit":  1,
+			"credit": 1,
+		}}},
+	}
+
+	var results []monthTotal
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		cursor, err := coll.Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		return cursor.All(ctx, &results)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	by_month := map[int]monthTotal{}
+	for _, result := range results {
+		by_month[yearMonthKey(result.Year, result.Month)] = result
+	}
+
+	totals := make([]monthTotal, num_months)
+	for i := range totals {
+		y, mo := addMonths(start_year, start_month, i)
+		total, ok := by_month[yearMonthKey(y, mo)]
+		if !ok {
+			total = monthTotal{Year: y, Month: mo}
+		}
+		totals[i] = total
+	}
+
+	return totals, nil
+}
+
+// Matches expenses between the dates of p (inclusive). A zero from or to
+// date leaves that end of the range open.
+func dateRangeFilter(p period) bson.M {
+	dates := bson.M{}
+	if !p.from.IsZero() {
+		dates["$gte"] = p.from
+	}
+	if !p.to.IsZero() {
+		dates["$lte"] = p.to
+	}
+
+	if len(dates) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"date": dates}
+}
+
+// Debit and credit totals per category for one month, largest spending first
+func mongoCategoryTotals(year int, month int) ([]categoryTotal, error) {
+	p := monthPeriod(year, month)
+	return mongoCategoryTotalsBetween(p.from, p.to)
+}
+
+// Debit and credit totals per category between two dates (inclusive),
+// largest spending first
+func mongoCategoryTotalsBetween(from time.Time, to time.Time) ([]categoryTotal, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: dateRangeFilter(period{from: from, to: to})}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$category", ""}}, ""}},
+				"$category",
+				uncategorized,
+			}},
+			"debit":  bson.M{"$sum": "$debit"},
+			"credit": bson.M{"$sum": "$credit"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "debit", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	var results []categoryTotal
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		cursor, err := coll.Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		return cursor.All(ctx, &results)
+	})
+
+	return results, err
+}
+
+// Credits minus debits of each account up to and including each of
+// dates, in the order of dates. Entries without an account are under "".
+func mongoAccountBalances(dates []time.Time) (map[string][]float64, error) {
+	group := bson.M{"_id": bson.M{"$ifNull": bson.A{"$account", ""}}}
+	balances := bson.A{}
+	last := time.Time{}
+	for idx, date := range dates {
+		key := "balance_" + strconv.Itoa(idx)
+		group[key] = bson.M{"$sum": bson.M{"$cond": bson.A{
+			bson.M{"$lte": bson.A{"$date", date}},
+			bson.M{"$subtract": bson.A{bson.M{"$ifNull": bson.A{"$credit", 0}}, bson.M{"$ifNull": bson.A{"$debit", 0}}}},
+			0,
+		}}}
+		balances = append(balances, "$"+key)
+		if date.After(last) {
+			last = date
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: dateRangeFilter(period{to: last})}},
+		{{Key: "$group", Value: group}},
+		{{Key: "$project", Value: bson.M{"balances": balances}}},
+	}
+
+	var results []struct {
+		Account  string    `bson:"_id"`
+		Balances []float64 `bson:"balances"`
+	}
+	err := withExpensesCollection(func(ctx context.Context, coll *mongo.Collection) error {
+		cursor, err := coll.Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		return cursor.All(ctx, &results)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	by_account := map[string][]float64{}
+	for _, result := range results {
+		by_account[result.Account] = result.Balances
+	}
+	return by_account, nil
+}
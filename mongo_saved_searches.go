@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Find criteria saved under a name, e.g. "Amazon over $100". Params are
+// the parameters of parseSearch, so relative dates like this-year are
+// evaluated each time the search runs.
+type savedSearch struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name   string             `bson:"name" json:"name"`
+	Params map[string]string  `bson:"params" json:"params"`
+}
+
+func mongoFindSavedSearches() ([]savedSearch, error) {
+	searches := []savedSearch{}
+
+	err := withCollection(MongoSavedSearchesCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+		search_cursor, err := coll.Find(ctx, bson.M{}, opts)
+		if err != nil {
+			return err
+		}
+		defer search_cursor.Close(ctx)
+
+		return search_cursor.All(ctx, &searches)
+	})
+
+	return searches, err
+}
+
+// Returns mongo.ErrNoDocuments if no search has the name
+func mongoFindSavedSearch(name string) (savedSearch, error) {
+	var saved savedSearch
+
+	err := withCollection(MongoSavedSearchesCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		return coll.FindOne(ctx, bson.M{"name": name}).Decode(&saved)
+	})
+
+	return saved, err
+}
+
+// Replaces the search with the same name, if any
+func mongoSaveSearch(saved savedSearch) error {
+	return withCollection(MongoSavedSearchesCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		saved.ID = primitive.NilObjectID
+		_, err := coll.ReplaceOne(ctx, bson.M{"name": saved.Name}, saved, options.Replace().SetUpsert(true))
+		return err
+	})
+}
+
+// Returns false if no search has the name
+func mongoDeleteSavedSearch(name string) (bool, error) {
+	found := false
+
+	err := withCollection(MongoSavedSearchesCollection, func(ctx context.Context, coll *mongo.Collection) error {
+		result, err := coll.DeleteOne(ctx, bson.M{"name": name})
+		if err != nil {
+			return err
+		}
+		found = result.DeletedCount > 0
+		return nil
+	})
+
+	return found, err
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Parses a SWIFT MT940 statement. Each :61: statement line is a
+// transaction and the :86: field following it describes it.
+func parseMT940Entries(data []byte) ([]Expense, error) {
+	fields, err := splitMT940Fields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Expense{}
+	account := ""
+	var entry *Expense
+
+	finish := func() {
+		if entry != nil {
+			checkValidEntryValues(entry)
+			entries = append(entries, *entry)
+			entry = nil
+		}
+	}
+
+	for _, field := range fields {
+		switch field.tag {
+		case "25":
+			account = strings.TrimSpace(field.value)
+		case "61":
+			finish()
+			parsed, err := parseMT940StatementLine(field.value)
+			if err != nil {
+				// keep the row so it shows up as invalid on the review screen
+				parsed = Expense{Description: strings.TrimSpace(field.value)}
+			}
+			parsed.Account = account
+			entry = &parsed
+		case "86":
+			if entry != nil {
+				description := parseMT940Information(field.value)
+				if description != "" {
+					entry.Description = description
+				}
+			}
+		case "62F", "62M":
+			finish()
+		}
+	}
+	finish()
+
+	if len(entries) == 0 {
+		return nil, errors.New("no MT940 transactions found")
+	}
+
+	return entries, nil
+}
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// Splits the statement into :tag: fields. Fields can span several lines;
+// continuation lines do not start with a colon.
+func splitMT940Fields(data []byte) ([]mt940Field, error) {
+	fields := []mt940Field{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || line == "-" || strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			end := strings.Index(line[1:], ":")
+			if end > 0 {
+				fields = append(fields, mt940Field{tag: line[1 : end+1], value: line[end+2:]})
+				continue
+			}
+		}
+
+		if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+
+	return fields, scanner.Err()
+}
+
+// A :61: line looks like 2407020702D200,50NMSCNONREF//BANKREF123
+// value date (YYMMDD), optional entry date (MMDD), debit/credit mark
+// (D, C, RD or RC), optional funds code, amount with a decimal comma,
+// transaction type (N/F/S + 3 characters), customer reference and an
+// optional bank reference after //.
+func parseMT940StatementLine(line string) (Expense, error) {
+	entry := Expense{}
+	line = strings.SplitN(line, "\n", 2)[0]
+
+	if len(line) < 6 {
+		return entry, errors.New("MT940 statement line too short")
+	}
+	date, err := time.Parse("060102", line[:6])
+	if err != nil {
+		return entry, err
+	}
+	entry.Year = date.Year()
+	entry.Month = int(date.Month())
+	entry.Day = date.Day()
+	rest := line[6:]
+
+	if len(rest) >= 4 && isDigits(rest[:4]) {
+		rest = rest[4:]
+	}
+
+	debit := false
+	switch {
+	case strings.HasPrefix(rest, "RD"):
+		rest = rest[2:]
+	case strings.HasPrefix(rest, "RC"):
+		debit = true
+		rest = rest[2:]
+	case strings.HasPrefix(rest, "D"):
+		debit = true
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "C"):
+		rest = rest[1:]
+	default:
+		return entry, errors.New("missing debit/credit mark")
+	}
+
+	// funds code is a single letter before the amount
+	if len(rest) > 0 && unicode.IsLetter(rune(rest[0])) {
+		rest = rest[1:]
+	}
+
+	amount_end := strings.IndexFunc(rest, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != ','
+	})
+	if amount_end <= 0 {
+		return entry, errors.New("missing amount")
+	}
+	val, err := strconv.ParseFloat(strings.Replace(rest[:amount_end], ",", ".", 1), 64)
+	if err != nil {
+		return entry, err
+	}
+	if debit {
+		entry.Debit = val
+	} else {
+		entry.Credit = val
+	}
+	rest = rest[amount_end:]
+
+	// transaction type identification, e.g. NMSC
+	if len(rest) >= 4 {
+		rest = rest[4:]
+	}
+
+	references := strings.SplitN(rest, "//", 2)
+	if len(references) == 2 {
+		entry.FITID = strings.TrimSpace(references[1])
+	} else if reference := strings.TrimSpace(references[0]); reference != "NONREF" {
+		entry.FITID = reference
+	}
+	entry.Description = strings.TrimSpace(references[0])
+
+	return entry, nil
+}
+
+// :86: is free text, but many banks use ?NN subfields: ?20-?29 hold the
+// purpose and ?32-?33 the counterparty name.
+func parseMT940Information(info string) string {
+	info = strings.ReplaceAll(info, "\n", "")
+
+	if !strings.Contains(info, "?") {
+		return strings.Join(strings.Fields(info), " ")
+	}
+
+	name := ""
+	purpose := []string{}
+	for _, subfield := range strings.Split(info, "?")[1:] {
+		if len(subfield) < 2 || !isDigits(subfield[:2]) {
+			continue
+		}
+		code, value := subfield[:2], strings.TrimSpace(subfield[2:])
+		switch {
+		case code == "32" || code == "33":
+			name += value
+		case code >= "20" && code <= "29":
+			purpose = append(purpose, value)
+		}
+	}
+
+	if name != "" {
+		return name
+	}
+	return strings.Join(strings.Fields(strings.Join(purpose, " ")), " ")
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,113 @@
+package main
+
+import "testing"
+
+func TestParseMT940Fixture(t *testing.T) {
+	entries, err := parseMT940Entries(readFixture(t, "jul.mt940"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := "37040044/0532013000"
+	checkEntries(t, entries, []Expense{
+		{Year: 2024, Month: 7, Day: 2, Description: "GOOGLE *Audible", Debit: 200.50, Account: account, FITID: "20240702-0001", Valid: true},
+		// ?32 counterparty name wins over the ?20-?29 purpose
+		{Year: 2024, Month: 7, Day: 2, Description: "REAL CDN SUPERSTORE #1", Debit: 800, Account: account, FITID: "20240702-0002", Valid: true},
+		{Year: 2024, Month: 7, Day: 3, Description: "TIM HORTONS #7629", Debit: 10, Account: account, FITID: "20240703-0001", Valid: true},
+		// :86: continued on the next line
+		{Year: 2024, Month: 8, Day: 10, Description: "PIKE PLACE STARBUCKS", Credit: 10, Account: account, FITID: "20240810-0001", Valid: true},
+	})
+}
+
+func TestParseMT940StatementLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Expense
+		ok   bool
+	}{
+		{
+			name: "debit with entry date",
+			line: "2407020702D200,50NMSCNONREF//BANK1",
+			want: Expense{Year: 2024, Month: 7, Day: 2, Debit: 200.50, FITID: "BANK1", Description: "NONREF"},
+			ok:   true,
+		},
+		{
+			name: "credit without entry date",
+			line: "240810C10,NTRFREF42",
+			want: Expense{Year: 2024, Month: 8, Day: 10, Credit: 10, FITID: "REF42", Description: "REF42"},
+			ok:   true,
+		},
+		{
+			name: "reversal of a credit is a debit",
+			line: "2407150715RC15,00NMSCNONREF//REV1",
+			want: Expense{Year: 2024, Month: 7, Day: 15, Debit: 15, FITID: "REV1", Description: "NONREF"},
+			ok:   true,
+		},
+		{
+			name: "reversal of a debit is a credit",
+			line: "2407160716RD9,99NMSCNONREF//REV2",
+			want: Expense{Year: 2024, Month: 7, Day: 16, Credit: 9.99, FITID: "REV2", Description: "NONREF"},
+			ok:   true,
+		},
+		{
+			name: "funds code before the amount",
+			line: "240717DR5,00NMSCNONREF",
+			want: Expense{Year: 2024, Month: 7, Day: 17, Debit: 5, Description: "NONREF"},
+			ok:   true,
+		},
+		{name: "missing mark", line: "240717X5,00NMSC", ok: false},
+		{name: "missing amount", line: "240717DNMSC", ok: false},
+		{name: "too short", line: "2407", ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry, err := parseMT940StatementLine(test.line)
+			if (err == nil) != test.ok {
+				t.Fatalf("error = %v, want ok %v", err, test.ok)
+			}
+			if test.ok {
+				checkEntries(t, []Expense{entry}, []Expense{test.want})
+			}
+		})
+	}
+}
+
+func TestParseMT940Information(t *testing.T) {
+	tests := []struct {
+		name string
+		info string
+		want string
+	}{
+		{name: "free text", info: "TIM HORTONS   #7629", want: "TIM HORTONS #7629"},
+		{name: "free text over lines", info: "PIKE PLACE\n STARBUCKS", want: "PIKE PLACE STARBUCKS"},
+		{name: "counterparty name wrapped mid-word", info: "166?00SEPA?20Groceries?32REAL CDN SUPER?33STORE", want: "REAL CDN SUPERSTORE"},
+		{name: "purpose when there is no name", info: "166?00SEPA?20Rent?21 July", want: "Rent July"},
+		{name: "subfields split over lines", info: "?20Gym\n?21membership", want: "Gym membership"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseMT940Information(test.info); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseMT940Reversals(t *testing.T) {
+	data := ":20:STMT\n:25:ACC1\n" +
+		":61:2407150715RC15,00NMSCNONREF//REV1\n:86:?20Refund reversed\n" +
+		":61:2407160716RD9,99NMSCNONREF//REV2\n:86:?32COFFEE SH\n?33OP\n" +
+		":62F:C240716EUR0,00\n-\n"
+
+	entries, err := parseMT940Entries([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEntries(t, entries, []Expense{
+		{Year: 2024, Month: 7, Day: 15, Description: "Refund reversed", Debit: 15, Account: "ACC1", FITID: "REV1", Valid: true},
+		{Year: 2024, Month: 7, Day: 16, Description: "COFFEE SHOP", Credit: 9.99, Account: "ACC1", FITID: "REV2", Valid: true},
+	})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ofxElement struct {
+	tag   string // upper case, closing tags start with "/"
+	value string // text following the tag, empty for aggregates
+}
+
+// Splits an OFX document into tags and the text that follows them.
+// Works for both OFX 1.x (SGML, leaf elements are not closed) and
+// OFX 2.x (XML) files, as well as QFX which is OFX with extra tags.
+func tokenizeOFX(data string) []ofxElement {
+	elements := []ofxElement{}
+
+	// skip the header, everything before <OFX>
+	start := strings.Index(strings.ToUpper(data), "<OFX>")
+	if start < 0 {
+		return elements
+	}
+	data = data[start:]
+
+	for len(data) > 0 {
+		open := strings.IndexByte(data, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(data[open:], '>')
+		if end < 0 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(data[open+1 : open+end]))
+		data = data[open+end+1:]
+
+		next := strings.IndexByte(data, '<')
+		value := data
+		if next >= 0 {
+			value = data[:next]
+		}
+
+		elements = append(elements, ofxElement{tag: tag, value: unescapeOFX(strings.TrimSpace(value))})
+	}
+
+	return elements
+}
+
+func unescapeOFX(s string) string {
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", "\"", "&apos;", "'", "&amp;", "&").Replace(s)
+}
+
+type ofxAccount struct {
+	bank_id string
+	id      string
+}
+
+func (a ofxAccount) String() string {
+	if a.bank_id == "" {
+		return a.id
+	}
+	return a.bank_id + " " + a.id
+}
+
+// Parses the transactions of an OFX/QFX bank or credit card statement.
+// FITID and the statement's account are kept on each expense so that
+// re-importing an overlapping statement does not create duplicates.
+func parseOFXEntries(data []byte) ([]Expense, error) {
+	elements := tokenizeOFX(string(data))
+	if len(elements) == 0 {
+		return nil, errors.New("not an OFX file, missing <OFX> tag")
+	}
+
+	entries := []Expense{}
+	account := ofxAccount{}
+	var transaction *Expense
+	in_account := false
+
+	for _, element := range elements {
+		switch element.tag {
+		case "BANKACCTFROM", "CCACCTFROM":
+			in_account = true
+			account = ofxAccount{}
+		case "/BANKACCTFROM", "/CCACCTFROM":
+			in_account = false
+		case "BANKID":
+			if in_account {
+				account.bank_id = element.value
+			}
+		case "ACCTID":
+			if in_account {
+				account.id = element.value
+			}
+		case "STMTTRN":
+			transaction = &Expense{}
+		case "/STMTTRN":
+			if transaction != nil {
+				// a file may hold statements of several accounts, each
+				// listed before its transactions
+				transaction.Account = account.String()
+				checkValidEntryValues(transaction)
+				entries = append(entries, *transaction)
+				transaction = nil
+			}
+		}
+
+		if transaction == nil {
+			continue
+		}
+
+		switch element.tag {
+		case "DTPOSTED":
+			parsedDate, err := parseOFXDate(element.value)
+			if err == nil {
+				transaction.Month = int(parsedDate.Month())
+				transaction.Day = parsedDate.Day()
+				transaction.Year = parsedDate.Year()
+			}
+		case "TRNAMT":
+			val, err := parseOFXAmount(element.value)
+			if err == nil {
+				if val < 0 {
+					transaction.Debit = -val
+				} else {
+					transaction.Credit = val
+				}
+			}
+		case "FITID":
+			transaction.FITID = element.value
+		case "NAME", "PAYEE":
+			if transaction.Description == "" {
+				transaction.Description = element.value
+			}
+		case "MEMO":
+			if transaction.Description == "" {
+				transaction.Description = element.value
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// OFX allows a comma as the decimal separator and some banks add
+// thousands separators, e.g. -1,234.56 or -1.234,56. The separator that
+// comes last is the decimal one, a lone comma only when at most two
+// digits follow it.
+func parseOFXAmount(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	comma := strings.LastIndexByte(s, ',')
+	dot := strings.LastIndexByte(s, '.')
+
+	switch {
+	case comma >= 0 && dot >= 0 && comma > dot:
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.Replace(s, ",", ".", 1)
+	case comma >= 0 && dot < 0 && strings.Count(s, ",") == 1 && len(s)-comma-1 <= 2:
+		s = strings.Replace(s, ",", ".", 1)
+	default:
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// OFX dates are YYYYMMDD optionally followed by a time and timezone,
+// e.g. 20240702120000.000[-5:EST]
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) < len(ofx_date_layout) {
+		return time.Time{}, errors.New("invalid OFX date")
+	}
+	return time.Parse(ofx_date_layout, s[:len(ofx_date_layout)])
+}
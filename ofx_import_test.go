@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func TestParseOFXFixture(t *testing.T) {
+	entries, err := parseOFXEntries(readFixture(t, "jul.ofx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := "000123456 1234567"
+	checkEntries(t, entries, []Expense{
+		{Year: 2024, Month: 7, Day: 2, Description: "GOOGLE *Audible", Debit: 200.50, Account: account, FITID: "202407020001", Valid: true},
+		{Year: 2024, Month: 7, Day: 2, Description: "REAL CDN SUPERSTORE #1", Debit: 800, Account: account, FITID: "202407020002", Valid: true},
+		{Year: 2024, Month: 7, Day: 3, Description: "TIM HORTONS #7629", Debit: 10, Account: account, FITID: "202407030001", Valid: true},
+		{Year: 2024, Month: 8, Day: 10, Description: "PIKE PLACE STARBUCKS", Credit: 10, Account: account, FITID: "202408100001", Valid: true},
+	})
+}
+
+func TestParseOFXAccountPerStatement(t *testing.T) {
+	data := "<OFX><BANKMSGSRSV1>" +
+		"<STMTRS><BANKACCTFROM><BANKID>1<ACCTID>111</BANKACCTFROM><BANKTRANLIST>" +
+		"<STMTTRN><DTPOSTED>20240701<TRNAMT>-5<FITID>A1<NAME>FIRST</STMTTRN>" +
+		"</BANKTRANLIST></STMTRS>" +
+		"<STMTRS><BANKACCTFROM><BANKID>1<ACCTID>222</BANKACCTFROM><BANKTRANLIST>" +
+		"<STMTTRN><DTPOSTED>20240702<TRNAMT>-6<FITID>B1<NAME>SECOND</STMTTRN>" +
+		"</BANKTRANLIST></STMTRS>" +
+		"</BANKMSGSRSV1><CREDITCARDMSGSRSV1>" +
+		"<CCSTMTRS><CCACCTFROM><ACCTID>4500</CCACCTFROM><BANKTRANLIST>" +
+		"<STMTTRN><DTPOSTED>20240703<TRNAMT>-7<FITID>C1<NAME>THIRD</STMTTRN>" +
+		"</BANKTRANLIST></CCSTMTRS>" +
+		"</CREDITCARDMSGSRSV1></OFX>"
+
+	entries, err := parseOFXEntries([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1 111", "1 222", "4500"}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for idx, account := range want {
+		if entries[idx].Account != account {
+			t.Errorf("entry %d: account = %q, want %q", idx, entries[idx].Account, account)
+		}
+	}
+}
+
+func TestParseOFXAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{value: "-200.50", want: -200.50},
+		{value: "10", want: 10},
+		{value: "-12,50", want: -12.50},
+		{value: "1,234.56", want: 1234.56},
+		{value: "-1.234,56", want: -1234.56},
+		{value: "1,234,567.89", want: 1234567.89},
+		{value: "1,234", want: 1234},
+		{value: " 3.5 ", want: 3.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := parseOFXAmount(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := parseOFXAmount("abc"); err == nil {
+		t.Error("parsed an amount from abc")
+	}
+}
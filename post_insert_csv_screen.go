@@ -1,6 +1,7 @@
 package main
 
 import (
+	"path/filepath"
 	"strconv"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -8,6 +9,7 @@ import (
 
 type postInsertCSVScreenModel struct {
 	expenses []Expense
+	files    []fileImportResult // empty for manual entries
 }
 
 const DateWidth = 5
@@ -42,13 +44,54 @@ func (m postInsertCSVScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m postInsertCSVScreenModel) View() string {
-	s := ""
-	s += displayLegend(s)
+	s := displayFileResults(m.files)
+	s = displayLegend(s)
 	s += displayExpenses(m.expenses)
 	s += "\n" + textStyle.Width(HomeScreenWidth).PaddingLeft(2).Render("Press Ctrl+C to go back to home screen.") + "\n"
 	return s
 }
 
+func displayFileResults(files []fileImportResult) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	s := textStyle.Width(DescriptionWidth).Render("File")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Inserted")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Rejected")
+	s += " | "
+	s += textStyle.Width(LegendWidth).Render("Status")
+	s += "\n"
+
+	for _, file := range files {
+		style := selectedStyle
+		status := "Imported"
+		if file.Error != "" {
+			style = errorStyle
+			status = file.Error
+		} else if file.Skipped {
+			style = inactiveStyle
+			status = "Skipped - already imported"
+		} else if file.allDuplicates() {
+			style = inactiveStyle
+			status = "All rows already imported"
+		}
+
+		line := style.Width(DescriptionWidth).Render(truncate(filepath.Base(file.Filename), DescriptionWidth))
+		line += " | "
+		line += style.Width(DefaultWidth).Render(strconv.Itoa(file.Inserted))
+		line += " | "
+		line += style.Width(DefaultWidth).Render(strconv.Itoa(file.Rejected))
+		line += " | "
+		line += style.Width(LegendWidth).Render(truncate(status, LegendWidth))
+		s += line + "\n"
+	}
+
+	return s + "\n"
+}
+
 func displayLegend(s string) string {
 	s += textStyle.Width(LegendWidth).Render("Legend") + "\n"
 	s += errorStyle.Width(LegendWidth).Render("Not inserted into DB - invalid or duplicate") + "\n"
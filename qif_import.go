@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Parses a Quicken Interchange Format file. Each record is a set of lines
+// starting with a field code (D date, T amount, P payee, M memo, L category)
+// and ends with a line containing "^".
+func parseQIFEntries(data []byte) ([]Expense, error) {
+	entries := []Expense{}
+	entry := Expense{}
+	empty := true
+	memo := ""
+
+	finish := func() {
+		if !empty {
+			if entry.Description == "" {
+				entry.Description = memo
+			}
+			checkValidEntryValues(&entry)
+			entries = append(entries, entry)
+		}
+		entry = Expense{}
+		empty = true
+		memo = ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch code {
+		case '!':
+			// header such as !Type:Bank, nothing to store
+			continue
+		case '^':
+			finish()
+			continue
+		case 'D':
+			year, month, day, err := parseQIFDate(value)
+			if err == nil {
+				entry.Year = year
+				entry.Month = month
+				entry.Day = day
+			}
+		case 'T', 'U':
+			val, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+			if err == nil {
+				entry.Debit = 0
+				entry.Credit = 0
+				if val < 0 {
+					entry.Debit = -val
+				} else {
+					entry.Credit = val
+				}
+			}
+		case 'P':
+			entry.Description = value
+		case 'M':
+			memo = value
+		case 'L':
+			// [Account] means a transfer, not a category
+			if !strings.HasPrefix(value, "[") {
+				entry.Category = value
+			}
+		}
+		empty = false
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// files do not always end with ^
+	finish()
+
+	if len(entries) == 0 {
+		return nil, errors.New("no QIF transactions found")
+	}
+
+	return entries, nil
+}
+
+// QIF dates are month first and come in many forms, e.g. 07/02/2024,
+// 7/2/24, 7/ 2'24 or 07-02-2024. An apostrophe marks a year after 2000.
+func parseQIFDate(s string) (int, int, int, error) {
+	s = strings.NewReplacer("'", "/", "-", "/", ".", "/", " ", "").Replace(s)
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 {
+		return 0, 0, 0, errors.New("invalid QIF date")
+	}
+
+	nums := [3]int{}
+	for i, part := range parts {
+		num, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, 0, 0, errors.New("invalid QIF date")
+		}
+		nums[i] = num
+	}
+
+	month, day, year := nums[0], nums[1], nums[2]
+	if year < 100 {
+		if year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+	if month < 1 || month > 12 || !isValidDay(year, month, day) {
+		return 0, 0, 0, errors.New("invalid QIF date")
+	}
+
+	return year, month, day, nil
+}
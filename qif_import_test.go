@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("test/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Compares parsed entries field by field so a failure names the entry
+func checkEntries(t *testing.T, got []Expense, want []Expense) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for idx := range want {
+		if !reflect.DeepEqual(got[idx], want[idx]) {
+			t.Errorf("entry %d:\n got %+v\nwant %+v", idx, got[idx], want[idx])
+		}
+	}
+}
+
+func TestParseQIFFixture(t *testing.T) {
+	entries, err := parseQIFEntries(readFixture(t, "jul.qif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, entries, []Expense{
+		{Year: 2024, Month: 7, Day: 2, Description: "GOOGLE *Audible", Debit: 200.50, Category: "Entertainment:Books", Valid: true},
+		{Year: 2024, Month: 7, Day: 2, Description: "REAL CDN SUPERSTORE #1", Debit: 800, Category: "Groceries", Valid: true},
+		{Year: 2024, Month: 7, Day: 3, Description: "TIM HORTONS #7629", Debit: 10, Category: "Dining", Valid: true},
+		// [Chequing] is a transfer account, not a category
+		{Year: 2024, Month: 8, Day: 10, Description: "PIKE PLACE STARBUCKS", Credit: 10, Valid: true},
+	})
+}
+
+func TestParseQIFEntries(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Expense
+	}{
+		{
+			name: "memo when there is no payee",
+			data: "!Type:CCard\nD12/31/2023\nT-1,234.56\nMAnnual fee\n^\n",
+			want: []Expense{{Year: 2023, Month: 12, Day: 31, Description: "Annual fee", Debit: 1234.56, Valid: true}},
+		},
+		{
+			name: "no closing caret",
+			data: "D1/5'24\nU25.00\nPREFUND\n",
+			want: []Expense{{Year: 2024, Month: 1, Day: 5, Description: "REFUND", Credit: 25, Valid: true}},
+		},
+		{
+			name: "day that does not exist is kept as invalid",
+			data: "D02/30/2024\nT-5\nPX\n^\n",
+			want: []Expense{{Description: "X", Debit: 5}},
+		},
+		{
+			name: "windows line endings",
+			data: "D07-04-2024\r\nT-3.50\r\nPCOFFEE\r\n^\r\n",
+			want: []Expense{{Year: 2024, Month: 7, Day: 4, Description: "COFFEE", Debit: 3.5, Valid: true}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := parseQIFEntries([]byte(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEntries(t, entries, test.want)
+		})
+	}
+
+	if _, err := parseQIFEntries([]byte("!Type:Bank\n")); err == nil {
+		t.Error("expected an error for a file without transactions")
+	}
+}
+
+func TestParseQIFDate(t *testing.T) {
+	tests := []struct {
+		in               string
+		year, month, day int
+		ok               bool
+	}{
+		{in: "07/02/2024", year: 2024, month: 7, day: 2, ok: true},
+		{in: "7/2/24", year: 2024, month: 7, day: 2, ok: true},
+		{in: "7/ 2'24", year: 2024, month: 7, day: 2, ok: true},
+		{in: "12/31/99", year: 1999, month: 12, day: 31, ok: true},
+		{in: "07.02.2024", year: 2024, month: 7, day: 2, ok: true},
+		{in: "02/29/2023", ok: false},
+		{in: "13/01/2024", ok: false},
+		{in: "2024-07", ok: false},
+	}
+
+	for _, test := range tests {
+		year, month, day, err := parseQIFDate(test.in)
+		if (err == nil) != test.ok {
+			t.Errorf("parseQIFDate(%q) error = %v, want ok %v", test.in, err, test.ok)
+			continue
+		}
+		if test.ok && (year != test.year || month != test.month || day != test.day) {
+			t.Errorf("parseQIFDate(%q) = %d-%d-%d, want %d-%d-%d", test.in, year, month, day, test.year, test.month, test.day)
+		}
+	}
+}
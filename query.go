@@ -0,0 +1,498 @@
+// Search query language used by the find screen, `budgie find --query`
+// and the API, e.g.
+//
+//	desc:tim date:2024-07-01..2024-07-31 debit>50 category:food -tag:reimbursed
+//
+// Terms are ANDed, OR combines the terms around it, parentheses group and
+// a leading - negates. Words without a field match the description.
+// desc~ takes a regular expression instead of literal text, and text:
+// runs a ranked search on the text index, e.g. text:"coffee shop".
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Fields a term can search, by the name used in queries
+var query_fields = map[string]string{
+	"desc":        "description",
+	"description": "description",
+	"date":        "date",
+	"year":        "year",
+	"month":       "month",
+	"day":         "day",
+	"debit":       "debit",
+	"credit":      "credit",
+	"amount":      "amount", // debit or credit
+	"category":    "category",
+	"cat":         "category",
+	"account":     "account",
+	"tag":         "tag",
+	"text":        "text", // words in the text index, ranked by relevance
+}
+
+var query_operators = []string{">=", "<=", ":", "=", ">", "<", "~"}
+
+type querySyntaxError struct {
+	pos int // byte offset in the query
+	msg string
+}
+
+func (e querySyntaxError) Error() string {
+	return fmt.Sprintf("at column %d: %s", e.pos+1, e.msg)
+}
+
+// A node of a parsed query. Compiles to a mongo filter.
+type queryNode interface {
+	filter() bson.M
+}
+
+type queryAnd struct{ nodes []queryNode }
+type queryOr struct{ nodes []queryNode }
+type queryNot struct{ node queryNode }
+
+// field op value, with the value already checked for the field
+type queryTerm struct {
+	pos    int
+	field  string
+	op     string
+	text   string
+	number float64
+	dates  period
+}
+
+func (q queryAnd) filter() bson.M {
+	filters := bson.A{}
+	for _, node := range q.nodes {
+		filters = append(filters, node.filter())
+	}
+	return bson.M{"$and": filters}
+}
+
+func (q queryOr) filter() bson.M {
+	filters := bson.A{}
+	for _, node := range q.nodes {
+		filters = append(filters, node.filter())
+	}
+	return bson.M{"$or": filters}
+}
+
+func (q queryNot) filter() bson.M {
+	return bson.M{"$nor": bson.A{q.node.filter()}}
+}
+
+var query_comparisons = map[string]string{">": "$gt", ">=": "$gte", "<": "$lt", "<=": "$lte"}
+
+func (q queryTerm) filter() bson.M {
+	if q.op == "~" {
+		return bson.M{queryTextField(q.field): regexFilter(q.text)}
+	}
+
+	switch q.field {
+	case "description":
+		return descriptionFilter(q.text)
+	case "category", "account", "tag":
+		return bson.M{queryTextField(q.field): exactTextFilter(q.text)}
+	case "text":
+		return bson.M{"$text": bson.M{"$search": q.text}}
+	case "date":
+		return dateRangeFilter(q.dates)
+	case "amount":
+		return eitherAmountFilter(numberFilter(q.op, q.number))
+	}
+	return bson.M{q.field: numberFilter(q.op, q.number)}
+}
+
+func numberFilter(op string, number float64) bson.M {
+	if comparison, ok := query_comparisons[op]; ok {
+		return bson.M{comparison: number}
+	}
+	return bson.M{"$eq": number}
+}
+
+// The stored field a text term matches
+func queryTextField(field string) string {
+	if field == "tag" {
+		return "tags"
+	}
+	return field
+}
+
+// Matches the whole text, ignoring case
+func exactTextFilter(text string) bson.M {
+	return bson.M{"$regex": "^" + regexp.QuoteMeta(text) + "$", "$options": "i"}
+}
+
+// Matches the regular expression anywhere in the value, ignoring case.
+// The expression must have been checked with regexp.Compile, which only
+// accepts the RE2 subset of the PCRE syntax MongoDB matches it with.
+func regexFilter(expression string) bson.M {
+	return bson.M{"$regex": expression, "$options": "i"}
+}
+
+// Matches descriptions containing text literally, ignoring case
+func descriptionFilter(text string) bson.M {
+	return bson.M{"description": bson.M{"$regex": regexp.QuoteMeta(text), "$options": "i"}}
+}
+
+// Whether node has a text: term, which allows sorting by relevance
+func hasTextTerm(node queryNode) bool {
+	switch node := node.(type) {
+	case queryAnd:
+		for _, child := range node.nodes {
+			if hasTextTerm(child) {
+				return true
+			}
+		}
+	case queryTerm:
+		return node.field == "text"
+	}
+	return false
+}
+
+type queryToken struct {
+	pos     int
+	text    string // "(", ")", "-", "OR" or a term
+	is_term bool   // text is a term even if it reads like an operator
+}
+
+type queryParser struct {
+	tokens []queryToken
+	idx    int
+	end    int // length of the query, for errors at the end
+}
+
+// Parses query, returning nil for an empty query
+func parseQuery(query string) (queryNode, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	p := &queryParser{tokens: tokens, end: len(query)}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.idx < len(p.tokens) {
+		return nil, querySyntaxError{p.tokens[p.idx].pos, "unexpected )"}
+	}
+	if err := checkTextTerms(node, false, new(int)); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// Storage runs at most one text search per query and cannot negate it or
+// combine it with OR, so text: may only be ANDed with other terms
+func checkTextTerms(node queryNode, nested bool, count *int) error {
+	switch node := node.(type) {
+	case queryAnd:
+		for _, child := range node.nodes {
+			if err := checkTextTerms(child, nested, count); err != nil {
+				return err
+			}
+		}
+	case queryOr:
+		for _, child := range node.nodes {
+			if err := checkTextTerms(child, true, count); err != nil {
+				return err
+			}
+		}
+	case queryNot:
+		return checkTextTerms(node.node, true, count)
+	case queryTerm:
+		if node.field != "text" {
+			return nil
+		}
+		if nested {
+			return querySyntaxError{node.pos, "text: cannot be negated or used with OR"}
+		}
+		*count++
+		if *count > 1 {
+			return querySyntaxError{node.pos, "only one text: term is allowed, put all the words in it"}
+		}
+	}
+	return nil
+}
+
+// Splits the query into parentheses, negations, OR and terms. Double
+// quotes keep spaces in a value, e.g. desc:"tim hortons".
+func lexQuery(query string) ([]queryToken, error) {
+	tokens := []queryToken{}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, queryToken{pos: i, text: string(c)})
+			i++
+		case c == '-' && i+1 < len(query) && !unicode.IsSpace(rune(query[i+1])) && query[i+1] != ')':
+			tokens = append(tokens, queryToken{pos: i, text: "-"})
+			i++
+		default:
+			start := i
+			text := ""
+			for i < len(query) && !unicode.IsSpace(rune(query[i])) && query[i] != '(' && query[i] != ')' {
+				if query[i] == '"' {
+					end := strings.IndexByte(query[i+1:], '"')
+					if end < 0 {
+						return nil, querySyntaxError{i, "unterminated quote"}
+					}
+					text += query[i+1 : i+1+end]
+					i += end + 2
+					continue
+				}
+				text += string(query[i])
+				i++
+			}
+			tokens = append(tokens, queryToken{pos: start, text: text, is_term: text != "OR"})
+		}
+	}
+
+	return tokens, nil
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.idx >= len(p.tokens) {
+		return queryToken{pos: p.end}, false
+	}
+	return p.tokens[p.idx], true
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	nodes := []queryNode{}
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+
+		token, ok := p.peek()
+		if !ok || token.is_term || token.text != "OR" {
+			break
+		}
+		p.idx++
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return queryOr{nodes: nodes}, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	nodes := []queryNode{}
+	for {
+		token, ok := p.peek()
+		if !ok || (!token.is_term && (token.text == ")" || token.text == "OR")) {
+			break
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	token, _ := p.peek()
+	switch len(nodes) {
+	case 0:
+		return nil, querySyntaxError{token.pos, "expected a search term"}
+	case 1:
+		return nodes[0], nil
+	}
+	return queryAnd{nodes: nodes}, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	token, _ := p.peek()
+	p.idx++
+	if token.is_term {
+		return parseQueryTerm(token)
+	}
+
+	switch token.text {
+	case "-":
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return queryNot{node: node}, nil
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.peek(); !ok || closing.is_term || closing.text != ")" {
+			return nil, querySyntaxError{closing.pos, "missing )"}
+		}
+		p.idx++
+		return node, nil
+	}
+
+	return parseQueryTerm(token)
+}
+
+func parseQueryTerm(token queryToken) (queryNode, error) {
+	name, op, value := splitQueryTerm(token.text)
+	if op == "" {
+		return queryTerm{pos: token.pos, field: "description", op: ":", text: token.text}, nil
+	}
+
+	field, ok := query_fields[strings.ToLower(name)]
+	if !ok {
+		return nil, querySyntaxError{token.pos, fmt.Sprintf("unknown field %q", name)}
+	}
+	value_pos := token.pos + len(name) + len(op)
+	if value == "" {
+		return nil, querySyntaxError{value_pos, "expected a value after " + name + op}
+	}
+
+	term := queryTerm{pos: token.pos, field: field, op: op, text: value}
+	if op == "~" && field != "description" && field != "category" && field != "account" && field != "tag" {
+		return nil, querySyntaxError{token.pos + len(name), field + " does not support ~"}
+	}
+	switch field {
+	case "description", "category", "account", "tag":
+		if op == "~" {
+			if _, err := regexp.Compile(value); err != nil {
+				return nil, querySyntaxError{value_pos, "invalid regular expression: " + err.Error()}
+			}
+		} else if op != ":" && op != "=" {
+			return nil, querySyntaxError{token.pos + len(name), field + " only supports :, = and ~"}
+		}
+	case "text":
+		if op != ":" {
+			return nil, querySyntaxError{token.pos + len(name), "text only supports :"}
+		}
+	case "date":
+		dates, err := parseQueryDates(op, value)
+		if err != nil {
+			return nil, querySyntaxError{value_pos, err.Error()}
+		}
+		term.dates = dates
+	case "month":
+		month, err := parseMonth(value)
+		if err != nil {
+			return nil, querySyntaxError{value_pos, err.Error()}
+		}
+		term.number = float64(month)
+	default:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, querySyntaxError{value_pos, fmt.Sprintf("invalid %s %q", field, value)}
+		}
+		term.number = number
+	}
+
+	return term, nil
+}
+
+// Splits "debit>=50" into "debit", ">=" and "50". The op is empty if the
+// text does not start with a field name followed by an operator.
+func splitQueryTerm(text string) (string, string, string) {
+	name_end := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsLetter(r) })
+	if name_end <= 0 {
+		return "", "", text
+	}
+	for _, op := range query_operators {
+		if strings.HasPrefix(text[name_end:], op) {
+			return text[:name_end], op, text[name_end+len(op):]
+		}
+	}
+	return "", "", text
+}
+
+// Dates matched by a date term. A value is a year, a month (YYYY-MM), a
+// day (YYYY-MM-DD) or a range of those separated by "..", where either
+// end may be left open.
+func parseQueryDates(op string, value string) (period, error) {
+	if from, to, is_range := strings.Cut(value, ".."); is_range {
+		if op != ":" && op != "=" {
+			return period{}, fmt.Errorf("date ranges only support : and =")
+		}
+		p := period{}
+		if from != "" {
+			first, _, err := parseQueryDate(from)
+			if err != nil {
+				return p, err
+			}
+			p.from = first
+		}
+		if to != "" {
+			_, last, err := parseQueryDate(to)
+			if err != nil {
+				return p, err
+			}
+			p.to = last
+		}
+		if !p.from.IsZero() && !p.to.IsZero() && p.to.Before(p.from) {
+			return p, fmt.Errorf("range ends before it starts")
+		}
+		return p, nil
+	}
+
+	first, last, err := parseQueryDate(value)
+	if err != nil {
+		return period{}, err
+	}
+	switch op {
+	case ">":
+		return period{from: last.AddDate(0, 0, 1)}, nil
+	case ">=":
+		return period{from: first}, nil
+	case "<":
+		return period{to: first.AddDate(0, 0, -1)}, nil
+	case "<=":
+		return period{to: last}, nil
+	}
+	return period{from: first, to: last}, nil
+}
+
+// First and last day of a year, month or day. today, this-month,
+// last-month, this-year and last-year are relative to now, so saved
+// searches keep up with the calendar.
+func parseQueryDate(value string) (time.Time, time.Time, error) {
+	now := time.Now()
+	this_month := dateOf(now.Year(), int(now.Month()), 1)
+	this_year := dateOf(now.Year(), 1, 1)
+	switch value {
+	case "today":
+		today := dateOf(now.Year(), int(now.Month()), now.Day())
+		return today, today, nil
+	case "this-month":
+		return this_month, this_month.AddDate(0, 1, -1), nil
+	case "last-month":
+		return this_month.AddDate(0, -1, 0), this_month.AddDate(0, 0, -1), nil
+	case "this-year":
+		return this_year, this_year.AddDate(1, 0, -1), nil
+	case "last-year":
+		return this_year.AddDate(-1, 0, 0), this_year.AddDate(0, 0, -1), nil
+	}
+
+	if date, err := time.Parse(date_layout, value); err == nil {
+		return date, date, nil
+	}
+	if date, err := time.Parse("2006-01", value); err == nil {
+		return date, date.AddDate(0, 1, -1), nil
+	}
+	if date, err := time.Parse("2006", value); err == nil {
+		return date, date.AddDate(1, 0, -1), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, format: YYYY, YYYY-MM, YYYY-MM-DD or this-month, last-year, etc.", value)
+}
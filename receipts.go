@@ -0,0 +1,347 @@
+// Matching a folder of receipt files to the expenses they paid for. The
+// date, total and merchant of a receipt come from its file name, e.g.
+// 2024-07-15_tim-hortons_12.50.jpg, or from a sidecar JSON file with the
+// same name and a .json extension, which takes precedence:
+//
+//	{"date": "2024-07-15", "total": 12.50, "merchant": "Tim Hortons"}
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Half a cent either way, so totals match the amounts as stored
+const receipt_amount_tolerance = 0.005
+
+// Debits are checked for missing receipts this far back unless a period
+// is given
+const receipt_lookback_months = 12
+
+type receipt struct {
+	Path     string  `json:"path"`
+	Hash     string  `json:"hash"`
+	Date     string  `json:"date"` // YYYY-MM-DD
+	Total    float64 `json:"total"`
+	Merchant string  `json:"merchant,omitempty"`
+}
+
+func (r receipt) day() time.Time {
+	day, _ := time.Parse(date_layout, r.Date)
+	return day
+}
+
+type receiptSidecar struct {
+	Date     string  `json:"date"`
+	Total    float64 `json:"total"`
+	Merchant string  `json:"merchant"`
+}
+
+// A receipt file whose date or total could not be found
+type receiptProblem struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Expenses a receipt may belong to, most likely first
+type receiptMatch struct {
+	Receipt    receipt   `json:"receipt"`
+	Candidates []Expense `json:"candidates"`
+
+	// ID of the expense the receipt is attached to, if it already was
+	// or was just attached
+	AttachedTo string `json:"attached_to,omitempty"`
+}
+
+// Reads the date, total and merchant from the parts of a file name
+// separated by underscores or spaces: a YYYY-MM-DD date, an amount, and
+// words of the merchant with dashes for spaces
+func parseReceiptName(name string) receiptSidecar {
+	parsed := receiptSidecar{}
+	merchant := []string{}
+
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	for _, part := range strings.FieldsFunc(base, func(r rune) bool { return r == '_' || r == ' ' }) {
+		if _, err := time.Parse(date_layout, part); err == nil && parsed.Date == "" {
+			parsed.Date = part
+			continue
+		}
+		if total, err := strconv.ParseFloat(strings.TrimPrefix(part, "$"), 64); err == nil && total > 0 && parsed.Total == 0 {
+			parsed.Total = total
+			continue
+		}
+		merchant = append(merchant, strings.ReplaceAll(part, "-", " "))
+	}
+
+	parsed.Merchant = strings.Join(merchant, " ")
+	return parsed
+}
+
+// Reads a receipt's metadata from its file name and sidecar
+func loadReceipt(path string) (receipt, error) {
+	r := receipt{Path: path}
+	parsed := parseReceiptName(filepath.Base(path))
+
+	for _, sidecar_path := range []string{path + ".json", strings.TrimSuffix(path, filepath.Ext(path)) + ".json"} {
+		data, err := os.ReadFile(sidecar_path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return r, err
+		}
+
+		sidecar := receiptSidecar{}
+		if err := json.Unmarshal(data, &sidecar); err != nil {
+			return r, errors.New(filepath.Base(sidecar_path) + ": " + err.Error())
+		}
+		if sidecar.Date != "" {
+			parsed.Date = sidecar.Date
+		}
+		if sidecar.Total != 0 {
+			parsed.Total = sidecar.Total
+		}
+		if sidecar.Merchant != "" {
+			parsed.Merchant = sidecar.Merchant
+		}
+		break
+	}
+
+	if _, err := time.Parse(date_layout, parsed.Date); err != nil {
+		return r, errors.New("no date, expected YYYY-MM-DD in the file name or sidecar")
+	}
+	if parsed.Total <= 0 {
+		return r, errors.New("no total in the file name or sidecar")
+	}
+	r.Date = parsed.Date
+	r.Total = parsed.Total
+	r.Merchant = parsed.Merchant
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	r.Hash = fileChecksum(data)
+	return r, nil
+}
+
+// Loads the images and PDFs in dir, sorted by date. Sidecars and other
+// files are left out, hidden files too.
+func scanReceipts(dir string) ([]receipt, []receiptProblem, error) {
+	dir_entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	receipts := []receipt{}
+	problems := []receiptProblem{}
+	for _, dir_entry := range dir_entries {
+		name := dir_entry.Name()
+		if dir_entry.IsDir() || strings.HasPrefix(name, ".") || !isAttachmentFile(name) {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		r, err := loadReceipt(path)
+		if err != nil {
+			problems = append(problems, receiptProblem{Path: path, Error: err.Error()})
+			continue
+		}
+		receipts = append(receipts, r)
+	}
+
+	sort.SliceStable(receipts, func(i, j int) bool { return receipts[i].Date < receipts[j].Date })
+	return receipts, problems, nil
+}
+
+// How many words of the merchant are in the description. Store numbers
+// are left out of both, see payeeKey.
+func merchantOverlap(merchant string, description string) int {
+	description_key := payeeKey(description)
+	overlap := 0
+	for _, word := range strings.Fields(payeeKey(merchant)) {
+		if strings.Contains(description_key, word) {
+			overlap++
+		}
+	}
+	return overlap
+}
+
+// Expenses with the receipt's total as debit or credit within
+// window_days of its date. The more merchant words the description has
+// the better, then the closer the date.
+func matchReceipt(r receipt, window_days int) (receiptMatch, error) {
+	match := receiptMatch{Receipt: r, Candidates: []Expense{}}
+
+	day := r.day()
+	search := entrySearch{
+		entry:      matchAllEntries(),
+		date_range: period{from: day.AddDate(0, 0, -window_days), to: day.AddDate(0, 0, window_days)},
+		amount_range: amountRange{
+			min:     r.Total - receipt_amount_tolerance,
+			max:     r.Total + receipt_amount_tolerance,
+			has_min: true,
+			has_max: true,
+		},
+	}
+	expenses, err := mongoFindEntries(search)
+	if err != nil {
+		return match, err
+	}
+
+	for _, entry := range expenses {
+		for _, a := range entry.Attachments {
+			if a.Hash == r.Hash {
+				match.AttachedTo = entry.ID.Hex()
+				return match, nil
+			}
+		}
+	}
+
+	sort.SliceStable(expenses, func(i, j int) bool {
+		overlap_i := merchantOverlap(r.Merchant, expenses[i].Description)
+		overlap_j := merchantOverlap(r.Merchant, expenses[j].Description)
+		if overlap_i != overlap_j {
+			return overlap_i > overlap_j
+		}
+		date_i := dateOf(expenses[i].Year, expenses[i].Month, expenses[i].Day)
+		date_j := dateOf(expenses[j].Year, expenses[j].Month, expenses[j].Day)
+		return math.Abs(date_i.Sub(day).Hours()) < math.Abs(date_j.Sub(day).Hours())
+	})
+	match.Candidates = expenses
+	return match, nil
+}
+
+func matchReceipts(receipts []receipt, window_days int) ([]receiptMatch, error) {
+	matches := []receiptMatch{}
+	for _, r := range receipts {
+		match, err := matchReceipt(r, window_days)
+		if err != nil {
+			return matches, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// Copies the receipt into the attachments directory and attaches it
+func attachReceipt(r receipt, entry Expense) error {
+	dir, err := attachmentsDir()
+	if err != nil {
+		return err
+	}
+	a, err := storeAttachment(dir, r.Path)
+	if err != nil {
+		return err
+	}
+	_, err = mongoAddEntryAttachments(entry.ID, []attachment{a})
+	return err
+}
+
+// The last receipt_lookback_months months up to today
+func defaultReceiptsPeriod(now time.Time) period {
+	today := dateOf(now.Year(), int(now.Month()), now.Day())
+	return period{from: today.AddDate(0, -receipt_lookback_months, 0), to: today}
+}
+
+func missingReceiptsSearch(threshold float64, p period) entrySearch {
+	return entrySearch{
+		entry:       matchAllEntries(),
+		date_range:  p,
+		debit_range: amountRange{min: threshold, has_min: true},
+		sort:        entrySort{column: sort_date, descending: true},
+	}
+}
+
+// The newest limit debits of at least threshold within p that have no
+// attachment, all of them if limit is 0
+func findMissingReceipts(threshold float64, p period, limit int64) ([]Expense, error) {
+	return mongoFindEntriesWithoutAttachments(missingReceiptsSearch(threshold, p), limit)
+}
+
+func countMissingReceipts(threshold float64, p period) (int64, error) {
+	return mongoCountEntriesWithoutAttachments(missingReceiptsSearch(threshold, p))
+}
+
+type receiptsResult struct {
+	Matches         []receiptMatch   `json:"matches"`
+	Unreadable      []receiptProblem `json:"unreadable"`
+	MissingReceipts []Expense        `json:"missing_receipts"`
+}
+
+// `budgie receipts [dir]` matches the receipt files in dir to expenses
+// and lists debits over the threshold without a receipt. With --attach,
+// receipts with a single candidate are attached to it.
+func runReceipts(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("receipts", flag.ContinueOnError)
+	window_days := flags.Int("window", cfg.ReceiptWindowDays, "days a receipt's date may differ from the expense")
+	threshold := flags.Float64("threshold", cfg.ReceiptThreshold, "debits of at least this amount need a receipt")
+	from := flags.String("from", "", "first date of the debits checked for receipts (default a year ago)")
+	to := flags.String("to", "", "last date of the debits checked for receipts")
+	attach := flags.Bool("attach", false, "attach receipts that match exactly one expense")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return usageError{errors.New("expected at most one receipts directory")}
+	}
+	if *window_days < 0 {
+		return usageError{errors.New("window must not be negative")}
+	}
+	p, err := parseOptionalPeriod(*from, *to)
+	if err != nil {
+		return usageError{err}
+	}
+	if *from == "" && *to == "" {
+		p = defaultReceiptsPeriod(time.Now())
+	}
+
+	result := receiptsResult{Matches: []receiptMatch{}, Unreadable: []receiptProblem{}}
+	if len(positional) == 1 {
+		receipts, problems, err := scanReceipts(expandHome(positional[0]))
+		if err != nil {
+			return err
+		}
+		result.Unreadable = problems
+
+		result.Matches, err = matchReceipts(receipts, *window_days)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *attach {
+		for idx, match := range result.Matches {
+			if match.AttachedTo != "" || len(match.Candidates) != 1 {
+				continue
+			}
+			if err := attachReceipt(match.Receipt, match.Candidates[0]); err != nil {
+				return fmt.Errorf("%s: %w", match.Receipt.Path, err)
+			}
+			result.Matches[idx].AttachedTo = match.Candidates[0].ID.Hex()
+		}
+	}
+
+	// after attaching, so receipts just attached are not reported missing
+	result.MissingReceipts, err = findMissingReceipts(*threshold, p, 0)
+	if err != nil {
+		return err
+	}
+	return writeJSONOutput(result)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const ReceiptNameWidth = 32
+
+// Matches a folder of receipts to expenses one receipt at a time, and
+// lists debits over the threshold that still lack a receipt
+type receiptsScreenModel struct {
+	dir           string
+	scanned       bool
+	matches       []receiptMatch
+	choices       []int // candidate shown for each match
+	unreadable    []receiptProblem
+	missing       []Expense // newest page of missing_count
+	missing_count int64
+	cursor        int
+	cfg           budgieConfig
+	feedback      string
+	failed        bool
+}
+
+func createReceiptsScreenModel() receiptsScreenModel {
+	m := receiptsScreenModel{feedback: default_feedback}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		m.feedback = "Error loading config: " + err.Error()
+		m.failed = true
+	}
+	m.cfg = cfg
+	return loadMissingReceipts(m)
+}
+
+// Counts the debits of the last receipt_lookback_months months without a
+// receipt and loads the newest page of them
+func loadMissingReceipts(m receiptsScreenModel) receiptsScreenModel {
+	p := defaultReceiptsPeriod(time.Now())
+	count, err := countMissingReceipts(m.cfg.ReceiptThreshold, p)
+	if err == nil {
+		m.missing, err = findMissingReceipts(m.cfg.ReceiptThreshold, p, num_entries_per_page)
+	}
+	if err != nil {
+		m.feedback = "Error finding expenses without receipts: " + err.Error()
+		m.failed = true
+		return m
+	}
+	m.missing_count = count
+	return m
+}
+
+func scanReceiptsDir(m receiptsScreenModel) receiptsScreenModel {
+	receipts, problems, err := scanReceipts(expandHome(m.dir))
+	if err != nil {
+		m.feedback = "Error reading receipts: " + err.Error()
+		m.failed = true
+		return m
+	}
+	matches, err := matchReceipts(receipts, m.cfg.ReceiptWindowDays)
+	if err != nil {
+		m.feedback = "Error matching receipts: " + err.Error()
+		m.failed = true
+		return m
+	}
+
+	m.scanned = true
+	m.matches = matches
+	m.choices = make([]int, len(matches))
+	m.unreadable = problems
+	m.cursor = 0
+	m.feedback = strconv.Itoa(len(matches)) + " receipts found."
+	m.failed = false
+	return m
+}
+
+func (m receiptsScreenModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m receiptsScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return createHomeScreenModel(), nil
+		}
+		if !m.scanned {
+			return updateReceiptsDir(m, msg), nil
+		}
+
+		switch msg.String() {
+
+		case "up":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down":
+			if m.cursor < len(m.matches)-1 {
+				m.cursor++
+			}
+
+		// cycles through the candidates of the receipt
+		case "left", "right":
+			if len(m.matches) == 0 || len(m.matches[m.cursor].Candidates) == 0 {
+				break
+			}
+			delta := 1
+			if msg.String() == "left" {
+				delta = -1
+			}
+			count := len(m.matches[m.cursor].Candidates)
+			m.choices[m.cursor] = (m.choices[m.cursor] + delta + count) % count
+
+		case "enter":
+			m = confirmReceiptMatch(m)
+
+		case "esc":
+			m.scanned = false
+			m.feedback = default_feedback
+		}
+	}
+
+	return m, nil
+}
+
+// Typing the receipts directory, enter scans it
+func updateReceiptsDir(m receiptsScreenModel, msg tea.KeyMsg) receiptsScreenModel {
+	switch msg.String() {
+	case "tab":
+		m.dir = completePath(m.dir, isAttachmentFile)
+	case "backspace":
+		m.dir = removeLastChar(m.dir)
+	case "enter":
+		if m.dir != "" {
+			m = scanReceiptsDir(m)
+		}
+	default:
+		m.dir += msg.String()
+	}
+	return m
+}
+
+// Attaches the receipt under the cursor to the candidate shown and moves
+// on to the next receipt
+func confirmReceiptMatch(m receiptsScreenModel) receiptsScreenModel {
+	if len(m.matches) == 0 {
+		return m
+	}
+	match := &m.matches[m.cursor]
+	if match.AttachedTo != "" || len(match.Candidates) == 0 {
+		return m
+	}
+
+	entry := match.Candidates[m.choices[m.cursor]]
+	if err := attachReceipt(match.Receipt, entry); err != nil {
+		m.feedback = "Error attaching receipt: " + err.Error()
+		m.failed = true
+		return m
+	}
+	match.AttachedTo = entry.ID.Hex()
+	m.feedback = "Attached " + filepath.Base(match.Receipt.Path) + " to " + entry.Description + "."
+	m.failed = false
+
+	if m.cursor < len(m.matches)-1 {
+		m.cursor++
+	}
+	return loadMissingReceipts(m)
+}
+
+func (m receiptsScreenModel) View() string {
+	s := selectedStyle.Width(HomeScreenWidth).Render("> Match receipts") + "\n"
+
+	if !m.scanned {
+		s += textStyle.Width(InsertScreenWidth).PaddingLeft(2).Render("Receipts folder:")
+		s += selectedStyle.PaddingLeft(2).PaddingRight(2).Render(m.dir) + "\n"
+		s += textStyle.PaddingLeft(2).Render("Images and PDFs named like 2024-07-15_tim-hortons_12.50.jpg, or with a .json sidecar. Press tab to complete, enter to scan.") + "\n"
+	} else {
+		s = renderReceiptMatches(m, s)
+	}
+
+	s = renderMissingReceipts(m, s)
+
+	if m.failed {
+		s += errorStyle.Render(m.feedback) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
+	return s
+}
+
+func renderReceiptMatches(m receiptsScreenModel, s string) string {
+	s += textStyle.Width(ReceiptNameWidth).Render("Receipt") + " | " +
+		textStyle.Width(DescriptionWidth).Render("Matching expense") + "\n"
+
+	for idx, match := range m.matches {
+		style := inactiveStyle
+		if idx == m.cursor {
+			style = selectedStyle
+		}
+		line := style.Width(ReceiptNameWidth).Render(truncate(filepath.Base(match.Receipt.Path), ReceiptNameWidth)) + " | "
+
+		switch {
+		case match.AttachedTo != "":
+			line += inactiveStyle.Render("attached")
+		case len(match.Candidates) == 0:
+			line += errorStyle.Render("no expense of " + formatAmount(match.Receipt.Total) + " around " + match.Receipt.Date)
+		default:
+			entry := match.Candidates[m.choices[idx]]
+			line += style.Width(DescriptionWidth).Render(truncate(entry.Description, DescriptionWidth)) + " " +
+				inactiveStyle.Render(dateOf(entry.Year, entry.Month, entry.Day).Format(date_layout)+"  "+formatAmount(match.Receipt.Total))
+			if len(match.Candidates) > 1 {
+				line += inactiveStyle.Render("  (" + strconv.Itoa(m.choices[idx]+1) + "/" + strconv.Itoa(len(match.Candidates)) + ")")
+			}
+		}
+		s += line + "\n"
+	}
+	for _, problem := range m.unreadable {
+		s += errorStyle.Render(filepath.Base(problem.Path)+": "+problem.Error) + "\n"
+	}
+
+	s += "\n" + textStyle.Render("Press enter to attach the receipt to the expense shown, left or right for other candidates, esc to pick another folder.") + "\n"
+	return s
+}
+
+// The newest expenses without a receipt, one page at most
+func renderMissingReceipts(m receiptsScreenModel, s string) string {
+	s += "\n" + textStyle.Render("Debits of "+formatAmount(m.cfg.ReceiptThreshold)+" or more in the last "+
+		strconv.Itoa(receipt_lookback_months)+" months without a receipt: "+strconv.FormatInt(m.missing_count, 10)) + "\n"
+	for _, entry := range m.missing {
+		s += inactiveStyle.Width(DefaultWidth).Render(dateOf(entry.Year, entry.Month, entry.Day).Format(date_layout)) +
+			inactiveStyle.Width(DescriptionWidth).Render(truncate(entry.Description, DescriptionWidth)) +
+			inactiveStyle.Render(formatAmount(entry.Debit)) + "\n"
+	}
+	if m.missing_count > int64(len(m.missing)) {
+		s += inactiveStyle.Render("...") + "\n"
+	}
+	return s + "\n"
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	schedule_monthly = iota
+	schedule_weekly  = iota
+	schedule_yearly  = iota
+)
+
+// When a recurring template is due, parsed from text like
+// "1st of every month", "last day of every month", "every friday",
+// "every 2 weeks on monday" or "every year on Jan 15"
+type schedule struct {
+	kind     int
+	day      int // day of the month, -1 for the last day
+	weekday  time.Weekday
+	month    time.Month
+	interval int // weeks between weekly entries
+}
+
+var (
+	monthly_schedule_regexp = regexp.MustCompile(`^(?:on the )?(last|\d{1,2})(?:st|nd|rd|th)?(?: day)? of (?:every|each) month$`)
+	weekly_schedule_regexp  = regexp.MustCompile(`^every (other |\d+ weeks? on )?([a-z]+)$`)
+	yearly_schedule_regexp  = regexp.MustCompile(`^every year on ([a-z]+) (\d{1,2})$`)
+)
+
+func parseSchedule(text string) (schedule, error) {
+	text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+
+	if match := monthly_schedule_regexp.FindStringSubmatch(text); match != nil {
+		if match[1] == "last" {
+			return schedule{kind: schedule_monthly, day: -1}, nil
+		}
+		day, _ := strconv.Atoi(match[1])
+		if day < 1 || day > 31 {
+			return schedule{}, errors.New("day of the month must be between 1 and 31")
+		}
+		return schedule{kind: schedule_monthly, day: day}, nil
+	}
+
+	if match := weekly_schedule_regexp.FindStringSubmatch(text); match != nil {
+		weekday, ok := parseWeekday(match[2])
+		if !ok {
+			return schedule{}, errors.New("unknown weekday " + match[2])
+		}
+		interval := 1
+		switch match[1] {
+		case "":
+		case "other ":
+			interval = 2
+		default:
+			interval, _ = strconv.Atoi(strings.Fields(match[1])[0])
+			if interval < 1 {
+				return schedule{}, errors.New("number of weeks must be at least 1")
+			}
+		}
+		return schedule{kind: schedule_weekly, weekday: weekday, interval: interval}, nil
+	}
+
+	if match := yearly_schedule_regexp.FindStringSubmatch(text); match != nil {
+		month, err := time.Parse("Jan", strings.ToUpper(match[1][:1])+match[1][1:min(3, len(match[1]))])
+		if err != nil {
+			return schedule{}, errors.New("unknown month " + match[1])
+		}
+		day, _ := strconv.Atoi(match[2])
+		if day < 1 || day > 31 {
+			return schedule{}, errors.New("day of the month must be between 1 and 31")
+		}
+		return schedule{kind: schedule_yearly, month: month.Month(), day: day}, nil
+	}
+
+	return schedule{}, errors.New(`unknown schedule, try "1st of every month", "every friday" or "every year on Jan 15"`)
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// Whether an entry is due on date. Weekly schedules count weeks from
+// the first matching weekday on or after start.
+func (s schedule) isDue(date time.Time, start time.Time) bool {
+	days_in_month := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	switch s.kind {
+	case schedule_monthly:
+		if s.day == -1 {
+			return date.Day() == days_in_month
+		}
+		// the 31st of every month falls on the 30th in April
+		return date.Day() == min(s.day, days_in_month)
+	case schedule_weekly:
+		if date.Weekday() != s.weekday {
+			return false
+		}
+		first := start.AddDate(0, 0, (int(s.weekday)-int(start.Weekday())+7)%7)
+		weeks := int(date.Sub(first).Hours()/24) / 7
+		return weeks%s.interval == 0
+	case schedule_yearly:
+		return date.Month() == s.month && date.Day() == min(s.day, days_in_month)
+	}
+	return false
+}
+
+// Due dates after the template was last generated, up to and including now
+func recurringDueDates(template recurringTemplate, now time.Time) ([]time.Time, error) {
+	s, err := parseSchedule(template.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.Parse(date_layout, template.StartDate)
+	if err != nil {
+		return nil, errors.New("invalid start date " + template.StartDate)
+	}
+
+	from := start
+	if template.GeneratedUntil != "" {
+		generated_until, err := time.Parse(date_layout, template.GeneratedUntil)
+		if err != nil {
+			return nil, errors.New("invalid generated until date " + template.GeneratedUntil)
+		}
+		from = generated_until.AddDate(0, 0, 1)
+	}
+
+	today := dateOf(now.Year(), int(now.Month()), now.Day())
+	dates := []time.Time{}
+	for date := from; !date.After(today); date = date.AddDate(0, 0, 1) {
+		if s.isDue(date, start) {
+			dates = append(dates, date)
+		}
+	}
+	return dates, nil
+}
+
+// The expense a template generates for a due date. The FITID identifies
+// the template and date so entries are never inserted twice.
+func recurringEntry(template recurringTemplate, date time.Time) Expense {
+	entry := Expense{
+		Year:        date.Year(),
+		Month:       int(date.Month()),
+		Day:         date.Day(),
+		Description: template.Description,
+		Debit:       template.Debit,
+		Credit:      template.Credit,
+		Category:    template.Category,
+		Account:     template.Account,
+		FITID:       "recurring-" + template.ID.Hex() + "-" + date.Format("20060102"),
+	}
+	checkValidEntryValues(&entry)
+	return entry
+}
+
+// Inserts the entries of all templates that became due since they were
+// last generated and returns how many were inserted
+func generateRecurringEntries(now time.Time) (int, error) {
+	templates, err := mongoFindRecurringTemplates()
+	if err != nil {
+		return 0, err
+	}
+
+	import_insert_lock.Lock()
+	defer import_insert_lock.Unlock()
+
+	generated := 0
+	for _, template := range templates {
+		dates, err := recurringDueDates(template, now)
+		if err != nil {
+			return generated, errors.New(template.Description + ": " + err.Error())
+		}
+		if len(dates) == 0 {
+			continue
+		}
+
+		entries := []Expense{}
+		for _, date := range dates {
+			entries = append(entries, recurringEntry(template, date))
+		}
+
+		inserted, err := mongoInsertValidEntries(entries)
+		generated += inserted
+		if err != nil {
+			return generated, err
+		}
+
+		last := dates[len(dates)-1].Format(date_layout)
+		if err := mongoSetRecurringGeneratedUntil(template.ID, last); err != nil {
+			return generated, err
+		}
+	}
+
+	return generated, nil
+}
+
+// Sent to the home screen once the entries due at startup are generated
+type recurringGeneratedMsg struct {
+	generated int
+	err       error
+}
+
+func generateRecurringEntriesCmd() tea.Msg {
+	generated, err := generateRecurringEntries(time.Now())
+	return recurringGeneratedMsg{generated: generated, err: err}
+}
+
+type recurringResult struct {
+	Generated int `json:"generated"`
+}
+
+// `budgie run-recurring` generates the entries that are due
+func runRecurringEntries(args []string) error {
+	flags := flag.NewFlagSet("run-recurring", flag.ContinueOnError)
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	generated, err := generateRecurringEntries(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := writeJSONOutput(recurringResult{Generated: generated}); err != nil {
+		return err
+	}
+	if generated == 0 {
+		return errNothingFound
+	}
+	return nil
+}
+
+// `budgie recurring add|list|delete` manages the templates
+func runRecurring(args []string) error {
+	if len(args) == 0 {
+		return usageError{errors.New("expected add, list or delete")}
+	}
+
+	switch args[0] {
+	case "add":
+		return runRecurringAdd(args[1:])
+	case "list":
+		templates, err := mongoFindRecurringTemplates()
+		if err != nil {
+			return err
+		}
+		if err := writeJSONOutput(templates); err != nil {
+			return err
+		}
+		if len(templates) == 0 {
+			return errNothingFound
+		}
+		return nil
+	case "delete":
+		if len(args) != 2 {
+			return usageError{errors.New("expected the ID of the template to delete")}
+		}
+		id, err := primitive.ObjectIDFromHex(args[1])
+		if err != nil {
+			return usageError{fmt.Errorf("invalid template id %q", args[1])}
+		}
+		found, err := mongoDeleteRecurringTemplate(id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errNothingFound
+		}
+		return nil
+	}
+
+	return usageError{errors.New("unknown recurring command " + args[0])}
+}
+
+func runRecurringAdd(args []string) error {
+	flags := flag.NewFlagSet("recurring add", flag.ContinueOnError)
+	description := flags.String("desc", "", "description of the generated entries")
+	debit := flags.Float64("debit", 0, "amount paid")
+	credit := flags.Float64("credit", 0, "amount received")
+	category := flags.String("category", "", "category of the generated entries")
+	account := flags.String("account", "", "account of the generated entries")
+	schedule_text := flags.String("schedule", "", `e.g. "1st of every month" or "every friday"`)
+	start := flags.String("start", time.Now().Format(date_layout), "first day entries can be due, YYYY-MM-DD")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *description == "" {
+		return usageError{errors.New("--desc is required")}
+	}
+	if *debit == 0 && *credit == 0 {
+		return usageError{errors.New("--debit or --credit is required")}
+	}
+	if _, err := parseSchedule(*schedule_text); err != nil {
+		return usageError{err}
+	}
+	if _, err := time.Parse(date_layout, *start); err != nil {
+		return usageError{errors.New("invalid start date, format: YYYY-MM-DD")}
+	}
+
+	template := recurringTemplate{
+		Description: *description,
+		Debit:       *debit,
+		Credit:      *credit,
+		Category:    *category,
+		Account:     *account,
+		Schedule:    *schedule_text,
+		StartDate:   *start,
+	}
+	id, err := mongoInsertRecurringTemplate(template)
+	if err != nil {
+		return err
+	}
+	template.ID = id
+	return writeJSONOutput(template)
+}
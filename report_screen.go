@@ -0,0 +1,161 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const ReportLabelWidth = 20
+
+type reportScreenModel struct {
+	year     int
+	month    int
+	summary  monthlySummary
+	feedback string
+	failed   bool
+}
+
+func createReportScreenModel() reportScreenModel {
+	now := time.Now()
+	m := reportScreenModel{
+		year:  now.Year(),
+		month: int(now.Month()),
+	}
+
+	return loadMonthlySummary(m)
+}
+
+func loadMonthlySummary(m reportScreenModel) reportScreenModel {
+	summary, err := mongoMonthlySummary(m.year, m.month)
+	if err != nil {
+		m.summary = monthlySummary{Year: m.year, Month: m.month}
+		m.feedback = "Error loading report: " + err.Error()
+		m.failed = true
+		return m
+	}
+
+	m.summary = summary
+	m.feedback = default_feedback
+	m.failed = false
+	return m
+}
+
+func (m reportScreenModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m reportScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+
+		switch msg.String() {
+
+		case "left":
+			m.year, m.month = addMonths(m.year, m.month, -1)
+			m = loadMonthlySummary(m)
+
+		case "right":
+			m.year, m.month = addMonths(m.year, m.month, 1)
+			m = loadMonthlySummary(m)
+
+		case "ctrl+c":
+			return createHomeScreenModel(), nil
+		}
+	}
+
+	return m, nil
+}
+
+// Moves year/month by delta months, wrapping across years
+func addMonths(year int, month int, delta int) (int, int) {
+	t := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC).AddDate(0, delta, 0)
+	return t.Year(), int(t.Month())
+}
+
+func (m reportScreenModel) View() string {
+	s := selectedStyle.Width(HomeScreenWidth).Render("> Reports") + "\n"
+	s += textStyle.PaddingLeft(2).PaddingRight(2).Render(
+		"< "+time.Month(m.month).String()+" "+strconv.Itoa(m.year)+" >") + "\n\n"
+
+	s = renderSummaryTotals(m.summary, s)
+	s = renderTopPayees(m.summary, s)
+	s = renderDailySpending(m.summary, s)
+
+	s += "\n" + textStyle.Render("Press < or > to switch months.") + "\n"
+	if m.failed {
+		s += errorStyle.Render(m.feedback) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
+	return s
+}
+
+func formatAmount(val float64) string {
+	return strconv.FormatFloat(val, 'f', 2, 64)
+}
+
+func renderSummaryTotals(summary monthlySummary, s string) string {
+	s += textStyle.PaddingLeft(2).Width(ReportLabelWidth).Render("Entries: ") +
+		inactiveStyle.PaddingLeft(2).Width(DefaultWidth).Render(strconv.Itoa(summary.Count)) + "\n"
+	s += textStyle.PaddingLeft(2).Width(ReportLabelWidth).Render("Total debits: ") +
+		inactiveStyle.PaddingLeft(2).Width(DefaultWidth).Render(formatAmount(summary.TotalDebit)) + "\n"
+	s += textStyle.PaddingLeft(2).Width(ReportLabelWidth).Render("Total credits: ") +
+		inactiveStyle.PaddingLeft(2).Width(DefaultWidth).Render(formatAmount(summary.TotalCredit)) + "\n"
+
+	net_style := selectedStyle
+	if summary.Net() < 0 {
+		net_style = errorStyle
+	}
+	s += textStyle.PaddingLeft(2).Width(ReportLabelWidth).Render("Net: ") +
+		net_style.PaddingLeft(2).Width(DefaultWidth).Render(formatAmount(summary.Net())) + "\n\n"
+
+	return s
+}
+
+func renderTopPayees(summary monthlySummary, s string) string {
+	s += textStyle.Width(DescriptionWidth+DefaultWidth*2+6).Render("Top payees") + "\n"
+	s += textStyle.Width(DescriptionWidth).Render("Description")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Spent")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Count")
+	s += "\n"
+
+	if len(summary.TopPayees) == 0 {
+		s += inactiveStyle.Width(DescriptionWidth).Render("No spending") + "\n"
+	}
+
+	for _, payee := range summary.TopPayees {
+		line := inactiveStyle.Width(DescriptionWidth).Render(payee.Description)
+		line += " | "
+		line += inactiveStyle.Width(DefaultWidth).Render(formatAmount(payee.Total))
+		line += " | "
+		line += inactiveStyle.Width(DefaultWidth).Render(strconv.Itoa(payee.Count))
+		s += line + "\n"
+	}
+
+	return s + "\n"
+}
+
+func renderDailySpending(summary monthlySummary, s string) string {
+	s += textStyle.Width(DateWidth+DefaultWidth+3).Render("Spending per day") + "\n"
+	s += textStyle.Width(DateWidth).Render("Day")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Spent")
+	s += "\n"
+
+	for _, day := range summary.DailyDebits {
+		if day.Debit == 0 {
+			continue
+		}
+		line := inactiveStyle.Width(DateWidth).Render(strconv.Itoa(day.Day))
+		line += " | "
+		line += inactiveStyle.Width(DefaultWidth).Render(formatAmount(day.Debit))
+		s += line + "\n"
+	}
+
+	return s
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"sort"
+	"strings"
+)
+
+func (s savedSearch) search() (entrySearch, error) {
+	return parseSearch(func(key string) string { return s.Params[key] })
+}
+
+// e.g. "min_debit=100 q=desc:amazon"
+func (s savedSearch) String() string {
+	params := []string{}
+	for key, value := range s.Params {
+		params = append(params, key+"="+value)
+	}
+	sort.Strings(params)
+	return strings.Join(params, " ")
+}
+
+// `budgie searches save|list|delete` manages saved searches, which
+// find and export run with --saved
+func runSearches(args []string) error {
+	if len(args) == 0 {
+		return usageError{errors.New("expected save, list or delete")}
+	}
+
+	switch args[0] {
+	case "save":
+		return runSaveSearch(args[1:])
+	case "list":
+		searches, err := mongoFindSavedSearches()
+		if err != nil {
+			return err
+		}
+		if err := writeJSONOutput(searches); err != nil {
+			return err
+		}
+		if len(searches) == 0 {
+			return errNothingFound
+		}
+		return nil
+	case "delete":
+		if len(args) != 2 {
+			return usageError{errors.New("expected the name of the search to delete")}
+		}
+		found, err := mongoDeleteSavedSearch(args[1])
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errNothingFound
+		}
+		return nil
+	}
+
+	return usageError{errors.New("unknown searches command " + args[0])}
+}
+
+func runSaveSearch(args []string) error {
+	flags := flag.NewFlagSet("searches save", flag.ContinueOnError)
+	search_params := addSearchParamFlags(flags)
+	names, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(names) != 1 {
+		return usageError{errors.New("expected the name to save the search under")}
+	}
+
+	params, err := search_params()
+	if err != nil {
+		return err
+	}
+	saved := savedSearch{Name: names[0], Params: params}
+	if _, err := saved.search(); err != nil {
+		return usageError{err}
+	}
+
+	if err := mongoSaveSearch(saved); err != nil {
+		return err
+	}
+	return writeJSONOutput(saved)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const SavedSearchNameWidth = 24
+
+type savedSearchesScreenModel struct {
+	searches []savedSearch
+	cursor   int
+	feedback string
+	failed   bool
+}
+
+func createSavedSearchesScreenModel() savedSearchesScreenModel {
+	m := savedSearchesScreenModel{
+		feedback: default_feedback,
+	}
+	return loadSavedSearches(m)
+}
+
+func loadSavedSearches(m savedSearchesScreenModel) savedSearchesScreenModel {
+	searches, err := mongoFindSavedSearches()
+	if err != nil {
+		m.feedback = "Error loading saved searches: " + err.Error()
+		m.failed = true
+	}
+	m.searches = searches
+	m.cursor = max(0, min(m.cursor, len(m.searches)-1))
+	return m
+}
+
+func (m savedSearchesScreenModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m savedSearchesScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+
+		switch msg.String() {
+
+		case "up":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down":
+			if m.cursor < len(m.searches)-1 {
+				m.cursor++
+			}
+
+		case "enter", "u", "d":
+			if len(m.searches) == 0 {
+				break
+			}
+			search, err := m.searches[m.cursor].search()
+			if err != nil {
+				m.feedback = "Invalid saved search: " + err.Error()
+				m.failed = true
+				break
+			}
+			if msg.String() == "d" {
+				return createDeleteEntriesModel(search), nil
+			}
+			return createUpdateEntriesModel(search), nil
+
+		case "r":
+			if len(m.searches) == 0 {
+				break
+			}
+			name := m.searches[m.cursor].Name
+			if _, err := mongoDeleteSavedSearch(name); err != nil {
+				m.feedback = "Error removing saved search: " + err.Error()
+				m.failed = true
+				break
+			}
+			m = loadSavedSearches(m)
+			m.feedback = "Removed saved search " + name + "."
+
+		case "ctrl+c":
+			return createHomeScreenModel(), nil
+		}
+	}
+
+	return m, nil
+}
+
+func (m savedSearchesScreenModel) View() string {
+	s := selectedStyle.Width(HomeScreenWidth).Render("> Saved searches") + "\n"
+
+	if len(m.searches) == 0 {
+		s += inactiveStyle.Width(DescriptionWidth).Render("No saved searches. Press ctrl+s in a search to save it.") + "\n"
+	}
+	for idx, saved := range m.searches {
+		style := inactiveStyle
+		if idx == m.cursor {
+			style = selectedStyle
+		}
+		s += style.Width(SavedSearchNameWidth).Render(truncate(saved.Name, SavedSearchNameWidth)) +
+			" " + inactiveStyle.Render(saved.String()) + "\n"
+	}
+
+	s += "\n" + textStyle.Render("Press enter or u to update the matching entries, d to delete them, r to remove the saved search.") + "\n"
+	if m.failed {
+		s += errorStyle.Render(m.feedback) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
+	return s
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Describes how to read statements from one bank. Profiles are named in
+// the config file; the default profile matches the layout of test/jan.csv.
+type importProfile struct {
+	// Go time layout of the csv date column
+	DateLayout string `json:"date_layout"`
+
+	// csv column numbers starting at 1, 0 if the file does not have the column
+	DateColumn        int `json:"date_column"`
+	DescriptionColumn int `json:"description_column"`
+	DebitColumn       int `json:"debit_column"`
+	CreditColumn      int `json:"credit_column"`
+	AmountColumn      int `json:"amount_column"` // signed amount, negative for debits
+	TotalColumn       int `json:"total_column"`
+
+	// stored on imported entries that do not name their own account
+	Account string `json:"account"`
+
+	// glob matched against file names by `budgie watch`, e.g. "visa-*.csv"
+	FilePattern string `json:"file_pattern"`
+}
+
+func defaultImportProfile() importProfile {
+	return importProfile{
+		DateLayout:        csv_date_layout,
+		DateColumn:        csv_date_col + 1,
+		DescriptionColumn: csv_description_col + 1,
+		DebitColumn:       csv_debit_col + 1,
+		CreditColumn:      csv_credit_col + 1,
+		TotalColumn:       csv_total_col + 1,
+	}
+}
+
+// An empty name selects the default profile
+func findImportProfile(name string) (importProfile, error) {
+	if name == "" {
+		return defaultImportProfile(), nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return importProfile{}, err
+	}
+
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return importProfile{}, errors.New("unknown import profile " + name)
+	}
+	if profile.DateLayout == "" {
+		profile.DateLayout = csv_date_layout
+	}
+	return profile, nil
+}
+
+// Picks the first profile, by name, whose file pattern matches the base
+// name of filename. Returns the default profile and an empty name if none do.
+func matchImportProfile(filename string) (string, importProfile, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", importProfile{}, err
+	}
+
+	names := []string{}
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	base := filepath.Base(filename)
+	for _, name := range names {
+		pattern := cfg.Profiles[name].FilePattern
+		if pattern == "" {
+			continue
+		}
+		if matched, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(base)); matched {
+			profile, err := findImportProfile(name)
+			return name, profile, err
+		}
+	}
+	return "", defaultImportProfile(), nil
+}
+
+type statementFormat struct {
+	name       string
+	extensions []string
+	parse      func(data []byte, profile importProfile) ([]Expense, error)
+}
+
+var statement_formats = []statementFormat{
+	{name: "csv", extensions: []string{".csv"}, parse: parseCSVData},
+	{name: "ofx", extensions: []string{".ofx", ".qfx"}, parse: ignoreProfile(parseOFXEntries)},
+	{name: "qif", extensions: []string{".qif"}, parse: ignoreProfile(parseQIFEntries)},
+	{name: "camt.053", extensions: []string{".xml", ".camt"}, parse: ignoreProfile(parseCamt053Entries)},
+	{name: "mt940", extensions: []string{".mt940", ".sta", ".940"}, parse: ignoreProfile(parseMT940Entries)},
+}
+
+// For formats that describe their own layout
+func ignoreProfile(parse func(data []byte) ([]Expense, error)) func(data []byte, profile importProfile) ([]Expense, error) {
+	return func(data []byte, profile importProfile) ([]Expense, error) {
+		return parse(data)
+	}
+}
+
+func parseCSVData(data []byte, profile importProfile) ([]Expense, error) {
+	reader, err := createCSVReader(data)
+	if err != nil {
+		return nil, err
+	}
+	return parseCSVEntries(reader, profile)
+}
+
+// Picks the statement format from the file extension, defaulting to csv
+func findStatementFormat(filename string) statementFormat {
+	extension := strings.ToLower(filepath.Ext(filename))
+	for _, format := range statement_formats {
+		for _, format_extension := range format.extensions {
+			if extension == format_extension {
+				return format
+			}
+		}
+	}
+	return statement_formats[0]
+}
+
+func isSupportedStatementFile(filename string) bool {
+	extension := strings.ToLower(filepath.Ext(filename))
+	for _, format := range statement_formats {
+		for _, format_extension := range format.extensions {
+			if extension == format_extension {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Parses a statement in memory. name is only used to pick the format.
+func parseStatement(name string, data []byte, profile importProfile) ([]Expense, error) {
+	entries, err := findStatementFormat(name).parse(data, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range entries {
+		if entries[idx].Account == "" {
+			entries[idx].Account = profile.Account
+		}
+	}
+	return entries, nil
+}
+
+func parseStatementFile(filename string, profile importProfile) ([]Expense, error) {
+	data, err := readStatementFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseStatement(filename, data, profile)
+}
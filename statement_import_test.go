@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSVData(t *testing.T) {
+	entries, err := parseCSVData(readFixture(t, "jan.csv"), defaultImportProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the header row is kept as an invalid entry
+	checkEntries(t, entries[1:], []Expense{
+		{Year: 2024, Month: 7, Day: 2, Description: "GOOGLE *Audible", Debit: 200.50, Total: 200.50, Valid: true},
+		{Year: 2024, Month: 7, Day: 2, Description: "REAL CDN SUPERSTORE #1", Debit: 800, Total: 1000.50, Valid: true},
+		{Year: 2024, Month: 7, Day: 3, Description: "TIM HORTONS #7629", Debit: 10, Total: 1010.50, Valid: true},
+		{Year: 2024, Month: 8, Day: 10, Description: "PIKE PLACE STARBUCKS", Credit: 10, Total: 1010.50, Valid: true},
+	})
+	if entries[0].Valid {
+		t.Errorf("header row is valid: %+v", entries[0])
+	}
+}
+
+func TestParseCSVDataMalformed(t *testing.T) {
+	data := "07/02/2024,GOOGLE,200.50,,\n07/03/2024,\"TIM \"HORTONS\",10.00,,\n"
+	entries, err := parseCSVData([]byte(data), defaultImportProfile())
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("err = %v, want an error on line 2", err)
+	}
+	if entries != nil {
+		t.Errorf("got a partial result: %+v", entries)
+	}
+}
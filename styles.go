@@ -31,3 +31,7 @@ var questionStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.Color("#FAFAFA")).
 	Background(lipgloss.Color("#ffbf00")).
 	Align(lipgloss.Left)
+
+var chartStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#32d147")).
+	Align(lipgloss.Left)
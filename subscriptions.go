@@ -0,0 +1,361 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// How far back charges are searched for, enough for three yearly charges
+const subscription_lookback_years = 3
+
+// A recurring charge needs at least this many payments to be detected
+const min_subscription_charges = 3
+
+// Charges more than this fraction away from the typical amount are left
+// out when looking for the cadence, they may be one-off purchases from
+// the same payee
+const subscription_amount_tolerance = 0.2
+
+// Share of the intervals between charges that must fit the cadence
+const subscription_regularity = 0.75
+
+// A subscription is considered cancelled after this many missed charges
+const subscription_missed_to_end = 3
+
+type cadence struct {
+	name      string
+	days      int // typical days between charges
+	tolerance int // days a charge may be early or late
+	months    int // calendar months between charges, 0 for fixed day intervals
+	per_year  float64
+}
+
+var cadences = []cadence{
+	{name: "weekly", days: 7, tolerance: 1, per_year: 52},
+	{name: "biweekly", days: 14, tolerance: 2, per_year: 26},
+	{name: "monthly", days: 30, tolerance: 4, months: 1, per_year: 12},
+	{name: "quarterly", days: 91, tolerance: 7, months: 3, per_year: 4},
+	{name: "yearly", days: 365, tolerance: 10, months: 12, per_year: 1},
+}
+
+// Adds n intervals of the cadence to date
+func (c cadence) after(date time.Time, n int) time.Time {
+	if c.months > 0 {
+		return date.AddDate(0, c.months*n, 0)
+	}
+	return date.AddDate(0, 0, c.days*n)
+}
+
+func findCadence(name string) (cadence, bool) {
+	for _, c := range cadences {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return cadence{}, false
+}
+
+// Whether days between two charges is close enough to the cadence
+func (c cadence) fits(days float64) bool {
+	return math.Abs(days-float64(c.days)) <= float64(c.tolerance)
+}
+
+// A payee charging a similar amount at a regular interval
+type subscription struct {
+	Payee      string   `json:"payee"`
+	Account    string   `json:"account,omitempty"`
+	Cadence    string   `json:"cadence"`
+	Amount     float64  `json:"amount"` // latest charge
+	AnnualCost float64  `json:"annual_cost"`
+	Charges    int      `json:"charges"`
+	LastCharge string   `json:"last_charge"`
+	NextCharge string   `json:"next_charge"`
+	Ended      bool     `json:"ended"`
+	Alerts     []string `json:"alerts"`
+}
+
+// Looks for subscriptions among the debits of the last few years
+func findSubscriptions(now time.Time) ([]subscription, error) {
+	from := now.AddDate(-subscription_lookback_years, 0, 0)
+	expenses, err := mongoFindEntries(entrySearch{entry: matchAllEntries(), date_range: period{from: from}})
+	if err != nil {
+		return nil, err
+	}
+	return detectSubscriptions(expenses, now), nil
+}
+
+// Groups debits by payee and keeps the groups charged at a regular
+// cadence. Active subscriptions come first, most expensive first.
+func detectSubscriptions(expenses []Expense, now time.Time) []subscription {
+	by_payee := map[string][]Expense{}
+	for _, entry := range expenses {
+		if entry.Debit <= 0 {
+			continue
+		}
+		key := payeeKey(entry.Description)
+		if key != "" {
+			by_payee[key] = append(by_payee[key], entry)
+		}
+	}
+
+	subscriptions := []subscription{}
+	for _, charges := range by_payee {
+		if found, ok := detectSubscription(charges, now); ok {
+			subscriptions = append(subscriptions, found)
+		}
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if subscriptions[i].Ended != subscriptions[j].Ended {
+			return !subscriptions[i].Ended
+		}
+		if subscriptions[i].AnnualCost != subscriptions[j].AnnualCost {
+			return subscriptions[i].AnnualCost > subscriptions[j].AnnualCost
+		}
+		return subscriptions[i].Payee < subscriptions[j].Payee
+	})
+	return subscriptions
+}
+
+func detectSubscription(charges []Expense, now time.Time) (subscription, bool) {
+	sort.SliceStable(charges, func(i, j int) bool {
+		return chargeDay(charges[i]).Before(chargeDay(charges[j]))
+	})
+
+	// the cadence comes from the charges of the typical amount, so
+	// one-off purchases from the same payee do not hide it
+	typical := medianOf(charges, func(e Expense) float64 { return e.Debit })
+	similar := []Expense{}
+	for _, entry := range charges {
+		if isTypicalCharge(entry, typical) {
+			similar = append(similar, entry)
+		}
+	}
+	if len(similar) < min_subscription_charges {
+		return subscription{}, false
+	}
+
+	intervals := []float64{}
+	for i := 1; i < len(similar); i++ {
+		intervals = append(intervals, chargeDay(similar[i]).Sub(chargeDay(similar[i-1])).Hours()/24)
+	}
+
+	c, ok := matchCadence(intervals)
+	if !ok {
+		return subscription{}, false
+	}
+
+	series := chargeSeries(charges, typical, c)
+	if len(series) < min_subscription_charges {
+		return subscription{}, false
+	}
+	latest := series[len(series)-1]
+	last := chargeDay(latest)
+	next := c.after(last, 1)
+
+	found := subscription{
+		Payee:      latest.Description,
+		Account:    latest.Account,
+		Cadence:    c.name,
+		Amount:     latest.Debit,
+		AnnualCost: latest.Debit * c.per_year,
+		Charges:    len(series),
+		LastCharge: last.Format(date_layout),
+		NextCharge: next.Format(date_layout),
+		Alerts:     []string{},
+	}
+
+	grace := time.Duration(c.tolerance) * 24 * time.Hour
+	if now.After(c.after(last, subscription_missed_to_end).Add(grace)) {
+		found.Ended = true
+		found.AnnualCost = 0
+		return found, true
+	}
+	if now.After(next.Add(grace)) {
+		found.Alerts = append(found.Alerts, "missing, expected around "+next.Format(date_layout))
+	}
+
+	previous := series[len(series)-2].Debit
+	if math.Abs(latest.Debit-previous) >= 0.01 {
+		found.Alerts = append(found.Alerts,
+			"amount changed from "+formatAmount(previous)+" to "+formatAmount(latest.Debit))
+	}
+
+	return found, true
+}
+
+// The latest run of charges at the cadence. A charge of the typical
+// amount followed by one at the next expected date starts the run, and
+// starts it again when more than subscription_missed_to_end charges were
+// missed. Of the charges around each expected date, the one closest in
+// amount to the charge before it joins whatever its amount, so a price
+// change keeps the run going.
+func chargeSeries(charges []Expense, typical float64, c cadence) []Expense {
+	tolerance := time.Duration(c.tolerance) * 24 * time.Hour
+	series := []Expense{}
+	last := time.Time{}
+
+	for idx := 0; idx < len(charges); {
+		day := chargeDay(charges[idx])
+		if len(series) == 0 || day.After(c.after(last, subscription_missed_to_end).Add(tolerance)) {
+			if isTypicalCharge(charges[idx], typical) && hasNextCharge(charges, idx, c) {
+				series = []Expense{charges[idx]}
+				last = day
+			}
+			idx++
+			continue
+		}
+
+		expected, ok := expectedCharge(c, last, day)
+		if !ok {
+			idx++
+			continue
+		}
+
+		previous := series[len(series)-1].Debit
+		best := idx
+		end := idx
+		for ; end < len(charges) && !chargeDay(charges[end]).After(expected.Add(tolerance)); end++ {
+			if math.Abs(charges[end].Debit-previous) < math.Abs(charges[best].Debit-previous) {
+				best = end
+			}
+		}
+		series = append(series, charges[best])
+		last = chargeDay(charges[best])
+		idx = end
+	}
+	return series
+}
+
+func isTypicalCharge(entry Expense, typical float64) bool {
+	return math.Abs(entry.Debit-typical) <= typical*subscription_amount_tolerance
+}
+
+// Whether a charge after charges[idx] falls on the next expected date
+func hasNextCharge(charges []Expense, idx int, c cadence) bool {
+	next := c.after(chargeDay(charges[idx]), 1)
+	for _, entry := range charges[idx+1:] {
+		if math.Abs(chargeDay(entry).Sub(next).Hours()/24) <= float64(c.tolerance) {
+			return true
+		}
+	}
+	return false
+}
+
+// The date a charge on day was expected on, if day is within the
+// tolerance of one of the next few charges after last
+func expectedCharge(c cadence, last time.Time, day time.Time) (time.Time, bool) {
+	if !day.After(last) {
+		return time.Time{}, false
+	}
+	for n := 1; n <= subscription_missed_to_end; n++ {
+		expected := c.after(last, n)
+		if math.Abs(day.Sub(expected).Hours()/24) <= float64(c.tolerance) {
+			return expected, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func chargeDay(entry Expense) time.Time {
+	return dateOf(entry.Year, entry.Month, entry.Day)
+}
+
+// Picks the cadence closest to the median interval, if enough of the
+// intervals fit it
+func matchCadence(intervals []float64) (cadence, bool) {
+	sorted := make([]float64, len(intervals))
+	copy(sorted, intervals)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	for _, c := range cadences {
+		if !c.fits(median) {
+			continue
+		}
+
+		regular := 0
+		for _, interval := range intervals {
+			if c.fits(interval) {
+				regular++
+			}
+		}
+		if float64(regular) >= float64(len(intervals))*subscription_regularity {
+			return c, true
+		}
+	}
+	return cadence{}, false
+}
+
+func medianOf(entries []Expense, value func(Expense) float64) float64 {
+	values := make([]float64, len(entries))
+	for i, entry := range entries {
+		values[i] = value(entry)
+	}
+	sort.Float64s(values)
+	return values[len(values)/2]
+}
+
+// Store numbers and reference codes change between charges from the same
+// payee, e.g. "TIM HORTONS #7629", so words with digits are left out
+func payeeKey(description string) string {
+	words := []string{}
+	for _, word := range strings.Fields(strings.ToUpper(description)) {
+		if strings.ContainsAny(word, "0123456789") {
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
+
+// `budgie subscriptions` lists detected subscriptions and their alerts
+func runSubscriptions(args []string) error {
+	flags := flag.NewFlagSet("subscriptions", flag.ContinueOnError)
+	as_json := flags.Bool("json", false, "print JSON instead of text")
+	alerts_only := flags.Bool("alerts", false, "only list subscriptions with alerts")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	subscriptions, err := findSubscriptions(time.Now())
+	if err != nil {
+		return err
+	}
+	if *alerts_only {
+		with_alerts := []subscription{}
+		for _, found := range subscriptions {
+			if len(found.Alerts) > 0 {
+				with_alerts = append(with_alerts, found)
+			}
+		}
+		subscriptions = with_alerts
+	}
+
+	if *as_json {
+		if err := writeJSONOutput(subscriptions); err != nil {
+			return err
+		}
+	} else {
+		for _, found := range subscriptions {
+			status := found.NextCharge
+			if found.Ended {
+				status = "ended"
+			}
+			fmt.Printf("%-36s %-10s %10s %12s/yr  next %s\n",
+				truncate(found.Payee, 36), found.Cadence, formatAmount(found.Amount), formatAmount(found.AnnualCost), status)
+			for _, alert := range found.Alerts {
+				fmt.Println("  !", alert)
+			}
+		}
+	}
+
+	if len(subscriptions) == 0 {
+		return errNothingFound
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const CadenceWidth = 10
+
+type subscriptionsScreenModel struct {
+	subscriptions []subscription
+	page_idx      int
+	feedback      string
+	failed        bool
+}
+
+func createSubscriptionsScreenModel() subscriptionsScreenModel {
+	m := subscriptionsScreenModel{
+		feedback: default_feedback,
+	}
+
+	subscriptions, err := findSubscriptions(time.Now())
+	if err != nil {
+		m.feedback = "Error detecting subscriptions: " + err.Error()
+		m.failed = true
+	}
+	m.subscriptions = subscriptions
+
+	return m
+}
+
+func (m subscriptionsScreenModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m subscriptionsScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+
+		switch msg.String() {
+
+		case "left":
+			if m.page_idx > 0 {
+				m.page_idx--
+			}
+		case "right":
+			if (m.page_idx+1)*num_entries_per_page < len(m.subscriptions) {
+				m.page_idx++
+			}
+
+		case "ctrl+c":
+			return createHomeScreenModel(), nil
+		}
+	}
+
+	return m, nil
+}
+
+func (m subscriptionsScreenModel) View() string {
+	s := selectedStyle.Width(HomeScreenWidth).Render("> Subscriptions") + "\n"
+	s = renderSubscriptionTotals(m, s)
+	s = renderSubscriptions(m, s)
+
+	s += "\n" + textStyle.Render("Press < or > to switch pages.") + "\n"
+	if m.failed {
+		s += errorStyle.Render(m.feedback) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
+	return s
+}
+
+func renderSubscriptionTotals(m subscriptionsScreenModel, s string) string {
+	active := 0
+	alerts := 0
+	annual := 0.0
+	for _, found := range m.subscriptions {
+		if !found.Ended {
+			active++
+			annual += found.AnnualCost
+		}
+		if len(found.Alerts) > 0 {
+			alerts++
+		}
+	}
+
+	s += textStyle.PaddingLeft(2).Width(ReportLabelWidth).Render("Active: ") +
+		inactiveStyle.PaddingLeft(2).Width(DefaultWidth).Render(strconv.Itoa(active)) + "\n"
+	s += textStyle.PaddingLeft(2).Width(ReportLabelWidth).Render("Cost per year: ") +
+		inactiveStyle.PaddingLeft(2).Width(DefaultWidth).Render(formatAmount(annual)) + "\n"
+
+	alert_style := inactiveStyle
+	if alerts > 0 {
+		alert_style = errorStyle
+	}
+	s += textStyle.PaddingLeft(2).Width(ReportLabelWidth).Render("Alerts: ") +
+		alert_style.PaddingLeft(2).Width(DefaultWidth).Render(strconv.Itoa(alerts)) + "\n\n"
+	return s
+}
+
+func renderSubscriptions(m subscriptionsScreenModel, s string) string {
+	s += textStyle.Width(DescriptionWidth).Render("Payee")
+	s += " | "
+	s += textStyle.Width(CadenceWidth).Render("Cadence")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Amount")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Per year")
+	s += " | "
+	s += textStyle.Width(DefaultWidth).Render("Next charge")
+	s += "\n"
+
+	if len(m.subscriptions) == 0 {
+		return s + inactiveStyle.Width(DescriptionWidth).Render("No subscriptions found") + "\n"
+	}
+
+	page_start := m.page_idx * num_entries_per_page
+	page_end := min(len(m.subscriptions), page_start+num_entries_per_page)
+
+	for _, found := range m.subscriptions[page_start:page_end] {
+		style := inactiveStyle
+		next := found.NextCharge
+		if len(found.Alerts) > 0 {
+			style = errorStyle
+		}
+		if found.Ended {
+			style = textStyle
+			next = "ended"
+		}
+
+		line := style.Width(DescriptionWidth).Render(truncate(found.Payee, DescriptionWidth))
+		line += " | "
+		line += style.Width(CadenceWidth).Render(found.Cadence)
+		line += " | "
+		line += style.Width(DefaultWidth).Render(formatAmount(found.Amount))
+		line += " | "
+		line += style.Width(DefaultWidth).Render(formatAmount(found.AnnualCost))
+		line += " | "
+		line += style.Width(DefaultWidth).Render(next)
+		s += line + "\n"
+
+		for _, alert := range found.Alerts {
+			s += errorStyle.PaddingLeft(2).PaddingRight(2).Render("! "+alert) + "\n"
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func monthlyCharges(payee string, amounts ...float64) []Expense {
+	charges := []Expense{}
+	for idx, amount := range amounts {
+		day := time.Date(2024, time.Month(1+idx), 15, 0, 0, 0, 0, time.UTC)
+		charges = append(charges, Expense{Year: day.Year(), Month: int(day.Month()), Day: day.Day(), Description: payee, Debit: amount})
+	}
+	return charges
+}
+
+func TestDetectSubscription(t *testing.T) {
+	tests := []struct {
+		name        string
+		charges     []Expense
+		now         time.Time
+		want_amount float64
+		want_count  int
+		want_alerts []string
+		want_ended  bool
+	}{
+		{
+			name:        "steady",
+			charges:     monthlyCharges("NETFLIX", 15.49, 15.49, 15.49, 15.49),
+			now:         time.Date(2024, 4, 20, 0, 0, 0, 0, time.UTC),
+			want_amount: 15.49,
+			want_count:  4,
+		},
+		{
+			name:        "price increase",
+			charges:     monthlyCharges("NETFLIX", 15.49, 15.49, 15.49, 15.49, 20.99),
+			now:         time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC),
+			want_amount: 20.99,
+			want_count:  5,
+			want_alerts: []string{"amount changed from 15.49 to 20.99"},
+		},
+		{
+			name: "one-off purchase between charges",
+			charges: append(monthlyCharges("APPLE.COM/BILL", 2.99, 2.99, 2.99),
+				Expense{Year: 2024, Month: 2, Day: 1, Description: "APPLE.COM/BILL", Debit: 1299}),
+			now:         time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC),
+			want_amount: 2.99,
+			want_count:  3,
+		},
+		{
+			name:        "missing",
+			charges:     monthlyCharges("SPOTIFY", 11.99, 11.99, 11.99),
+			now:         time.Date(2024, 4, 25, 0, 0, 0, 0, time.UTC),
+			want_amount: 11.99,
+			want_count:  3,
+			want_alerts: []string{"missing, expected around 2024-04-15"},
+		},
+		{
+			name:        "ended",
+			charges:     monthlyCharges("SPOTIFY", 11.99, 11.99, 11.99),
+			now:         time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC),
+			want_amount: 11.99,
+			want_count:  3,
+			want_ended:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found, ok := detectSubscription(test.charges, test.now)
+			if !ok {
+				t.Fatal("no subscription found")
+			}
+			if found.Cadence != "monthly" || found.Amount != test.want_amount || found.Charges != test.want_count || found.Ended != test.want_ended {
+				t.Errorf("got %+v", found)
+			}
+			if strings.Join(found.Alerts, "; ") != strings.Join(test.want_alerts, "; ") {
+				t.Errorf("alerts = %q, want %q", found.Alerts, test.want_alerts)
+			}
+		})
+	}
+}
+
+// An early charge of the same amount that does not fit the cadence must
+// not hold back the charges after it
+func TestDetectSubscriptionLateStart(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	charges := []Expense{}
+	for _, days := range []int{0, 100, 130, 160, 190} {
+		day := start.AddDate(0, 0, days)
+		charges = append(charges, Expense{Year: day.Year(), Month: int(day.Month()), Day: day.Day(), Description: "GYM", Debit: 40})
+	}
+
+	found, ok := detectSubscription(charges, start.AddDate(0, 0, 195))
+	if !ok {
+		t.Fatal("no subscription found")
+	}
+	last := start.AddDate(0, 0, 190).Format(date_layout)
+	if found.Cadence != "monthly" || found.Charges != 4 || found.LastCharge != last || found.Ended || found.AnnualCost != 480 {
+		t.Errorf("got %+v, want 4 monthly charges up to %s", found, last)
+	}
+	if len(found.Alerts) != 0 {
+		t.Errorf("alerts = %q", found.Alerts)
+	}
+}
+
+func TestDetectSubscriptionIrregular(t *testing.T) {
+	charges := []Expense{
+		{Year: 2024, Month: 1, Day: 3, Description: "TIM HORTONS", Debit: 4.5},
+		{Year: 2024, Month: 1, Day: 20, Description: "TIM HORTONS", Debit: 4.5},
+		{Year: 2024, Month: 3, Day: 2, Description: "TIM HORTONS", Debit: 4.5},
+		{Year: 2024, Month: 3, Day: 9, Description: "TIM HORTONS", Debit: 4.5},
+	}
+	if found, ok := detectSubscription(charges, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)); ok {
+		t.Errorf("found %+v", found)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const num_trend_months = 12
+
+type trendsScreenModel struct {
+	year            int
+	month           int
+	monthly_totals  []monthTotal
+	category_totals []categoryTotal
+	feedback        string
+	failed          bool
+}
+
+func createTrendsScreenModel() trendsScreenModel {
+	now := time.Now()
+	m := trendsScreenModel{
+		year:  now.Year(),
+		month: int(now.Month()),
+	}
+
+	return loadTrends(m)
+}
+
+func loadTrends(m trendsScreenModel) trendsScreenModel {
+	m.feedback = default_feedback
+	m.failed = false
+
+	monthly_totals, err := mongoMonthlyTotals(m.year, m.month, num_trend_months)
+	if err != nil {
+		m.feedback = "Error loading monthly totals: " + err.Error()
+		m.failed = true
+	}
+	m.monthly_totals = monthly_totals
+
+	category_totals, err := mongoCategoryTotals(m.year, m.month)
+	if err != nil {
+		m.feedback = "Error loading category totals: " + err.Error()
+		m.failed = true
+	}
+	m.category_totals = category_totals
+
+	return m
+}
+
+func (m trendsScreenModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m trendsScreenModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.KeyMsg:
+
+		switch msg.String() {
+
+		case "left":
+			m.year, m.month = addMonths(m.year, m.month, -1)
+			m = loadTrends(m)
+
+		case "right":
+			m.year, m.month = addMonths(m.year, m.month, 1)
+			m = loadTrends(m)
+
+		case "ctrl+c":
+			return createHomeScreenModel(), nil
+		}
+	}
+
+	return m, nil
+}
+
+func (m trendsScreenModel) View() string {
+	s := selectedStyle.Width(HomeScreenWidth).Render("> Spending trends") + "\n"
+	s += textStyle.PaddingLeft(2).PaddingRight(2).Render(
+		"< "+time.Month(m.month).String()+" "+strconv.Itoa(m.year)+" >") + "\n\n"
+
+	s = renderMonthlyTrend(m, s)
+	s = renderCategoryBreakdown(m, s)
+
+	s += "\n" + textStyle.Render("Press < or > to switch months.") + "\n"
+	if m.failed {
+		s += errorStyle.Render(m.feedback) + "\n"
+	} else {
+		s += textStyle.Render(m.feedback) + "\n"
+	}
+	return s
+}
+
+func renderMonthlyTrend(m trendsScreenModel, s string) string {
+	labels := make([]string, len(m.monthly_totals))
+	values := make([]float64, len(m.monthly_totals))
+	for i, total := range m.monthly_totals {
+		labels[i] = time.Month(total.Month).String()[:3] + " " + strconv.Itoa(total.Year)
+		values[i] = total.Debit
+	}
+
+	s += textStyle.PaddingRight(2).Render("Spending over the last " + strconv.Itoa(num_trend_months) + " months")
+	s += " " + renderSparkline(values) + "\n"
+	s += renderBarChart(labels, values, ChartBarWidth) + "\n"
+
+	return s
+}
+
+func renderCategoryBreakdown(m trendsScreenModel, s string) string {
+	s += textStyle.PaddingRight(2).Render("Spending per category in "+time.Month(m.month).String()) + "\n"
+
+	labels := []string{}
+	values := []float64{}
+	for _, total := range m.category_totals {
+		if total.Debit == 0 {
+			continue
+		}
+		labels = append(labels, total.Category)
+		values = append(values, total.Debit)
+	}
+
+	if len(labels) == 0 {
+		s += inactiveStyle.Width(ChartLabelWidth).Render("No spending") + "\n"
+		return s
+	}
+
+	s += renderBarChart(labels, values, ChartBarWidth)
+	return s
+}
@@ -5,6 +5,7 @@ package main
 
 import (
 	"strconv"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -19,39 +20,55 @@ const (
 
 type edit_table struct {
 	cursor   cursor2D
-	valid    [max_entries][expense_credit + 2]int
-	modified [max_entries][expense_credit + 2]int
+	valid    [num_entries_per_page][expense_credit + 2]int
+	modified [num_entries_per_page][expense_credit + 2]int
 }
 
 type updateEntriesModel struct {
-	entry_to_search        Expense
-	active_view            int
-	feedback               string
-	found_entries          []Expense
-	entries                []expensePlaceholder
-	found_entries_page_idx int
-	edit_table             edit_table
-	prompt_text            string
-	prompt_text_style      int
+	active_view       int
+	feedback          string
+	pager             entryPager
+	entries           []expensePlaceholder
+	edit_table        edit_table
+	prompt_text       string
+	prompt_text_style int
 }
 
-func createUpdateEntriesModel(found_entries []Expense, entry_to_search Expense) updateEntriesModel {
+func createUpdateEntriesModel(search entrySearch) updateEntriesModel {
+	return createUpdateEntriesModelAt(search, 0)
+}
+
+func createUpdateEntriesModelAt(search entrySearch, page_idx int) updateEntriesModel {
 	model := updateEntriesModel{
-		entry_to_search: entry_to_search,
-		found_entries:   found_entries,
-		feedback:        default_feedback,
-		active_view:     update_entries_view,
-		prompt_text:     default_feedback,
+		pager:       entryPager{search: search},
+		feedback:    default_feedback,
+		active_view: update_entries_view,
+		prompt_text: default_feedback,
 	}
 
-	return populateUpdateEntries(model)
+	return loadUpdatePage(model, page_idx)
+}
+
+// Fetches a page of the search. Edits on the current page are dropped,
+// so callers check anyEntryModified first.
+func loadUpdatePage(m updateEntriesModel, page_idx int) updateEntriesModel {
+	pager, err := loadEntryPage(m.pager.search, page_idx)
+	if err != nil {
+		m.prompt_text = "Error searching entries: " + err.Error()
+		m.prompt_text_style = 1
+		return m
+	}
+
+	m.pager = pager
+	m.edit_table = edit_table{}
+	return populateUpdateEntries(m)
 }
 
 func populateUpdateEntries(m updateEntriesModel) updateEntriesModel {
 
-	m.entries = make([]expensePlaceholder, len(m.found_entries))
+	m.entries = make([]expensePlaceholder, len(m.pager.entries))
 
-	for idx, entry := range m.found_entries {
+	for idx, entry := range m.pager.entries {
 		m.entries[idx].Year = strconv.Itoa(entry.Year)
 		m.entries[idx].Month = strconv.Itoa(entry.Month)
 		m.entries[idx].Day = strconv.Itoa(entry.Day)
@@ -72,7 +89,19 @@ func (m updateEntriesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 
-		switch msg.String() {
+		// < and > switch pages as in the delete list, except in the
+		// description where they can be typed
+		key := msg.String()
+		if m.edit_table.cursor.x != expense_description {
+			switch key {
+			case "<":
+				key = "pgup"
+			case ">":
+				key = "pgdown"
+			}
+		}
+
+		switch key {
 
 		case "up":
 			if m.active_view == update_entries_view {
@@ -82,8 +111,7 @@ func (m updateEntriesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case "down":
 			if m.active_view == update_entries_view {
-				num_entries_on_page := min(num_entries_per_page, len(m.found_entries)-(m.found_entries_page_idx*num_entries_per_page))
-				if m.edit_table.cursor.y < num_entries_on_page-1 {
+				if m.edit_table.cursor.y < len(m.pager.entries)-1 {
 					m.edit_table.cursor.y++
 				}
 			}
@@ -98,8 +126,7 @@ func (m updateEntriesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.edit_table.cursor.x < expense_credit {
 				m.edit_table.cursor.x++
 			} else {
-				num_entries_on_page := min(num_entries_per_page, len(m.found_entries)-(m.found_entries_page_idx*num_entries_per_page))
-				if m.edit_table.cursor.y < num_entries_on_page-1 {
+				if m.edit_table.cursor.y < len(m.pager.entries)-1 {
 					m.edit_table.cursor.y++
 					m.edit_table.cursor.x = 0
 				}
@@ -124,6 +151,45 @@ func (m updateEntriesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			checkIfEntryModified(&m, m.edit_table.cursor.y)
 		case "tab":
 			m.active_view = (m.active_view + 1) % update_num_views
+		case "ctrl+o", "ctrl+r":
+			if anyEntryModified(m) {
+				m.prompt_text = "Save the modified entries before sorting."
+				m.prompt_text_style = 1
+				break
+			}
+			m.pager.search.sort = nextEntrySort(m.pager.search, msg.String())
+			m = loadUpdatePage(m, 0)
+		case "ctrl+e":
+			if len(m.pager.entries) == 0 {
+				break
+			}
+			if anyEntryModified(m) {
+				m.prompt_text = "Save the modified entries before editing notes and attachments."
+				m.prompt_text_style = 1
+				break
+			}
+			return createEntryDetailsModel(m.pager.entries[m.edit_table.cursor.y], m.pager), nil
+		case "pgup", "pgdown":
+			if anyEntryModified(m) {
+				m.prompt_text = "Save the modified entries before switching pages."
+				m.prompt_text_style = 1
+				break
+			}
+			delta := 1
+			if key == "pgup" {
+				delta = -1
+			}
+			pager, err := m.pager.turn(delta)
+			if err != nil {
+				m.prompt_text = "Error searching entries: " + err.Error()
+				m.prompt_text_style = 1
+				break
+			}
+			if pager.page_idx != m.pager.page_idx {
+				m.pager = pager
+				m.edit_table = edit_table{}
+				m = populateUpdateEntries(m)
+			}
 		case "enter":
 			if m.active_view == update_action_view {
 
@@ -131,10 +197,11 @@ func (m updateEntriesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				// get entries being modified
 				original_entries_being_modified := []Expense{}
-				for row := 0; row < len(m.found_entries); row++ {
+				for row := 0; row < len(m.pager.entries); row++ {
 					for col := 0; col < (expense_credit + 1); col++ {
 						if m.edit_table.modified[row][col] == 1 {
-							original_entries_being_modified = append(original_entries_being_modified, m.found_entries[row])
+							original_entries_being_modified = append(original_entries_being_modified, m.pager.entries[row])
+							break
 						}
 					}
 				}
@@ -194,9 +261,20 @@ func (m updateEntriesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func anyEntryModified(m updateEntriesModel) bool {
+	for row := 0; row < len(m.pager.entries); row++ {
+		for col := 0; col < (expense_credit + 1); col++ {
+			if m.edit_table.modified[row][col] == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func checkForInvalidEntries(m *updateEntriesModel) bool {
 	any_entry_invalid := false
-	for y := 0; y < len(m.found_entries); y++ {
+	for y := 0; y < len(m.pager.entries); y++ {
 		for x := 0; x < (expense_credit + 1); x++ {
 			if (m.edit_table.valid[y][x]) == error_style {
 				any_entry_invalid = true
@@ -209,9 +287,10 @@ func checkForInvalidEntries(m *updateEntriesModel) bool {
 func getValidModifiedEntries(m *updateEntriesModel) []Expense {
 	entries := []Expense{}
 
-	for row := 0; row < len(m.found_entries); row++ {
+	for row := 0; row < len(m.pager.entries); row++ {
 
-		entry := Expense{}
+		// fields that are not shown in the table are kept as is
+		entry := Expense{Category: m.pager.entries[row].Category}
 
 		// see if fields are valid
 		for col := 0; col < (expense_credit + 1); col++ {
@@ -258,7 +337,7 @@ func getValidModifiedEntries(m *updateEntriesModel) []Expense {
 			case expense_day:
 				if m.entries[row].Day != "" {
 					day, err := strconv.Atoi(m.entries[row].Day)
-					if err == nil && day >= 1 && day <= 31 {
+					if err == nil && isValidDay(entry.Year, entry.Month, day) {
 						entry.Day = day
 						m.edit_table.valid[row][col] = selected_style
 					} else {
@@ -335,37 +414,37 @@ func getValidModifiedEntries(m *updateEntriesModel) []Expense {
 
 func checkIfEntryModified(m *updateEntriesModel, row int) {
 
-	if strconv.Itoa(m.found_entries[row].Year) != m.entries[row].Year {
+	if strconv.Itoa(m.pager.entries[row].Year) != m.entries[row].Year {
 		m.edit_table.modified[row][expense_year] = 1
 	} else {
 		m.edit_table.modified[row][expense_year] = 0
 	}
 
-	if strconv.Itoa(m.found_entries[row].Month) != m.entries[row].Month {
+	if strconv.Itoa(m.pager.entries[row].Month) != m.entries[row].Month {
 		m.edit_table.modified[row][expense_month] = 1
 	} else {
 		m.edit_table.modified[row][expense_month] = 0
 	}
 
-	if strconv.Itoa(m.found_entries[row].Day) != m.entries[row].Day {
+	if strconv.Itoa(m.pager.entries[row].Day) != m.entries[row].Day {
 		m.edit_table.modified[row][expense_day] = 1
 	} else {
 		m.edit_table.modified[row][expense_day] = 0
 	}
 
-	if m.found_entries[row].Description != m.entries[row].Description {
+	if m.pager.entries[row].Description != m.entries[row].Description {
 		m.edit_table.modified[row][expense_description] = 1
 	} else {
 		m.edit_table.modified[row][expense_description] = 0
 	}
 
-	if strconv.FormatFloat(m.found_entries[row].Debit, 'f', 2, 64) != m.entries[row].Debit {
+	if strconv.FormatFloat(m.pager.entries[row].Debit, 'f', 2, 64) != m.entries[row].Debit {
 		m.edit_table.modified[row][expense_debit] = 1
 	} else {
 		m.edit_table.modified[row][expense_debit] = 0
 	}
 
-	if strconv.FormatFloat(m.found_entries[row].Credit, 'f', 2, 64) != m.entries[row].Credit {
+	if strconv.FormatFloat(m.pager.entries[row].Credit, 'f', 2, 64) != m.entries[row].Credit {
 		m.edit_table.modified[row][expense_credit] = 1
 	} else {
 		m.edit_table.modified[row][expense_credit] = 0
@@ -389,19 +468,14 @@ func renderUpdateExpenses(m updateEntriesModel, s string) string {
 
 	s += "\n" + textStyle.Width((DateWidth+3)*3).Render("Matching Entries")
 
-	if len(m.found_entries) > 0 {
-		page_str := "Entries: " +
-			strconv.Itoa(m.found_entries_page_idx*num_entries_per_page+1) + "-" +
-			strconv.Itoa(min((m.found_entries_page_idx+1)*num_entries_per_page, len(m.found_entries))) + " / " +
-			strconv.Itoa(len(m.found_entries))
-
-		s += textStyle.Width(DescriptionWidth + 3).Render(page_str)
-		s += textStyle.Width((DefaultWidth + 3) * 2).Render("Press < or > to switch pages")
+	if len(m.pager.entries) > 0 {
+		s += textStyle.Width(DescriptionWidth + 3).Render(m.pager.status())
+		s += textStyle.Width((DefaultWidth + 3) * 2).Render("Press < > or pgup pgdown to switch pages")
 		s += activeUpdateViewStyle(m.active_view, update_entries_view).Width(3).Render(sym)
 	}
 
-	s += "\n" + textStyle.Render("Press tab to switch between search, entry, and delete sections.")
-	s += "\n"
+	s += "\n" + textStyle.Render("Press tab to switch between search, entry, and delete sections, ctrl+e for the notes and attachments of an entry.")
+	s += "\n" + renderSortHint(m.pager.search.sort)
 	s += textStyle.Width(DateWidth).Render("Year")
 	s += " | "
 	s += textStyle.Width(DateWidth).Render("Month")
@@ -413,6 +487,8 @@ func renderUpdateExpenses(m updateEntriesModel, s string) string {
 	s += textStyle.Width(DefaultWidth).Render("Debit")
 	s += " | "
 	s += textStyle.Width(DefaultWidth).Render("Credit")
+	s += " | "
+	s += textStyle.Width(CategoryWidth).Render("Category")
 	s += "\n"
 
 	// slice entries
@@ -430,6 +506,9 @@ func renderUpdateExpenses(m updateEntriesModel, s string) string {
 		line += selectUpdateEntryStyle(m, row, expense_debit).Width(DefaultWidth).Render(entry.Debit)
 		line += " | "
 		line += selectUpdateEntryStyle(m, row, expense_credit).Width(DefaultWidth).Render(entry.Credit)
+		line += " | "
+		line += inactiveStyle.Width(CategoryWidth).Render(truncate(m.pager.entries[row].Category, CategoryWidth))
+		line += " " + inactiveStyle.Render(entryExtrasHint(m.pager.entries[row]))
 
 		s += line + "\n"
 	}
@@ -437,6 +516,22 @@ func renderUpdateExpenses(m updateEntriesModel, s string) string {
 	return s
 }
 
+// e.g. "notes, 2 attachments"
+func entryExtrasHint(entry Expense) string {
+	extras := []string{}
+	if entry.Notes != "" {
+		extras = append(extras, "notes")
+	}
+	switch len(entry.Attachments) {
+	case 0:
+	case 1:
+		extras = append(extras, "1 attachment")
+	default:
+		extras = append(extras, strconv.Itoa(len(entry.Attachments))+" attachments")
+	}
+	return strings.Join(extras, ", ")
+}
+
 func renderUpdateActions(m updateEntriesModel, s string) string {
 	s += "\n" + textStyle.PaddingRight(2).Render("Edit selected entries?")
 
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const default_watch_interval = 10 * time.Second
+const default_archive_dir = "archive"
+const default_watch_log = "budgie-watch.log"
+
+// Size and modification time of a file when it was last polled
+type fileState struct {
+	size     int64
+	mod_time time.Time
+}
+
+type folderWatcher struct {
+	dir         string
+	archive_dir string
+	logger      *log.Logger
+
+	// files seen on the previous poll; a file is only imported once it
+	// stopped changing so downloads still being written are left alone
+	pending map[string]fileState
+	// files that failed to import, retried once they change
+	failed map[string]fileState
+}
+
+// `budgie watch <dir>` polls dir for new statements, imports them with the
+// profile whose file pattern matches and moves them into the archive folder
+func runWatch(args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := flags.Duration("interval", default_watch_interval, "time between polls")
+	archive := flags.String("archive", "", "folder for processed files (default <dir>/"+default_archive_dir+")")
+	log_path := flags.String("log", "", "log file (default <dir>/"+default_watch_log+")")
+	once := flags.Bool("once", false, "import the files that are there and exit")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError{errors.New("expected the folder to watch")}
+	}
+	if *interval <= 0 {
+		return usageError{errors.New("interval must be positive")}
+	}
+
+	dir := expandHome(positional[0])
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return usageError{errors.New(dir + " is not a folder")}
+	}
+
+	if *archive == "" {
+		*archive = filepath.Join(dir, default_archive_dir)
+	}
+	if *log_path == "" {
+		*log_path = filepath.Join(dir, default_watch_log)
+	}
+	if err := os.MkdirAll(*archive, 0o755); err != nil {
+		return err
+	}
+
+	log_file, err := os.OpenFile(*log_path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer log_file.Close()
+
+	watcher := folderWatcher{
+		dir:         dir,
+		archive_dir: *archive,
+		logger:      log.New(io.MultiWriter(os.Stderr, log_file), "", log.LstdFlags),
+		pending:     map[string]fileState{},
+		failed:      map[string]fileState{},
+	}
+
+	if *once {
+		// there is no earlier poll to compare against, so take everything
+		watcher.poll(true)
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	watcher.logger.Printf("watching %s every %s", dir, *interval)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		watcher.poll(false)
+		select {
+		case <-ctx.Done():
+			watcher.logger.Printf("stopped watching %s", dir)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Imports the statement files that did not change since the previous poll.
+// With all, files are imported even if they were not seen before.
+func (w *folderWatcher) poll(all bool) {
+	dir_entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		w.logger.Printf("error reading %s: %v", w.dir, err)
+		return
+	}
+
+	current := map[string]fileState{}
+	for _, dir_entry := range dir_entries {
+		if dir_entry.IsDir() || !isSupportedStatementFile(dir_entry.Name()) {
+			continue
+		}
+		info, err := dir_entry.Info()
+		if err != nil {
+			continue
+		}
+
+		filename := filepath.Join(w.dir, dir_entry.Name())
+		state := fileState{size: info.Size(), mod_time: info.ModTime()}
+		current[filename] = state
+
+		if failed_state, ok := w.failed[filename]; ok && failed_state == state {
+			continue
+		}
+		if previous, ok := w.pending[filename]; (ok && previous == state) || all {
+			w.importFile(filename, state)
+		}
+	}
+
+	w.pending = current
+}
+
+func (w *folderWatcher) importFile(filename string, state fileState) {
+	name := filepath.Base(filename)
+
+	profile_name, profile, err := matchImportProfile(filename)
+	if err != nil {
+		w.logger.Printf("failed %s: %v", name, err)
+		w.failed[filename] = state
+		return
+	}
+	if profile_name == "" {
+		profile_name = "default"
+	}
+
+	result := importStatementFiles([]string{filename}, profile, false, false)[0]
+	switch {
+	case result.Error != "":
+		w.logger.Printf("failed %s (profile %s): %s", name, profile_name, result.Error)
+		w.failed[filename] = state
+		return
+	case result.Skipped:
+		w.logger.Printf("skipped %s: already imported", name)
+	case result.allDuplicates():
+		w.logger.Printf("skipped %s: all %d already imported", name, result.Duplicates)
+	case result.Inserted == 0 && result.Rejected > result.Duplicates:
+		// left in place so it can be retried once the profile is fixed
+		w.logger.Printf("failed %s (profile %s): nothing inserted, %d rejected", name, profile_name, result.Rejected)
+		w.failed[filename] = state
+		return
+	default:
+		w.logger.Printf("imported %s (profile %s): %d inserted, %d rejected",
+			name, profile_name, result.Inserted, result.Rejected)
+		for _, entry := range result.Expenses {
+			if !entry.Valid {
+				w.logger.Printf("  rejected %04d-%02d-%02d %q debit %s credit %s",
+					entry.Year, entry.Month, entry.Day, entry.Description,
+					formatAmount(entry.Debit), formatAmount(entry.Credit))
+			}
+		}
+	}
+
+	delete(w.failed, filename)
+	archived, err := archiveFile(filename, w.archive_dir)
+	if err != nil {
+		w.logger.Printf("error archiving %s: %v", name, err)
+		w.failed[filename] = state
+		return
+	}
+	w.logger.Printf("moved %s to %s", name, archived)
+}
+
+// Moves filename into dir, adding a number to the name if it is taken
+func archiveFile(filename string, dir string) (string, error) {
+	base := filepath.Base(filename)
+	extension := filepath.Ext(base)
+	stem := base[:len(base)-len(extension)]
+
+	target := filepath.Join(dir, base)
+	for n := 1; ; n++ {
+		if _, err := os.Stat(target); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		target = filepath.Join(dir, stem+"-"+strconv.Itoa(n)+extension)
+	}
+
+	return target, os.Rename(filename, target)
+}
@@ -0,0 +1,106 @@
+// HTML reports served next to the REST API by `budgie serve`
+
+package main
+
+import (
+	"html/template"
+	"log"
+	"math"
+	"net/http"
+	"time"
+)
+
+var forecast_report_template = template.Must(template.New("forecast").Funcs(template.FuncMap{
+	"amount": formatAmount,
+	"barWidth": func(balance float64, max_balance float64) int {
+		if max_balance == 0 {
+			return 0
+		}
+		return int(math.Round(math.Abs(balance) / max_balance * 100))
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>budgie - cash-flow forecast</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 0.3em 0.8em; text-align: left; }
+td.amount { text-align: right; font-variant-numeric: tabular-nums; }
+tr.actual { background: #e8f8ea; }
+tr.forecast { background: #fff6dd; font-style: italic; color: #665; }
+tr.forecast td.kind::before { content: "~ "; }
+.negative { color: #c0143c; }
+.bar { height: 0.8em; background: #32d147; }
+tr.forecast .bar { background: repeating-linear-gradient(90deg, #ffbf00 0 6px, transparent 6px 10px); }
+.legend span { padding: 0.2em 0.6em; margin-right: 1em; }
+</style>
+</head>
+<body>
+<h1>Cash-flow forecast</h1>
+<p>Generated {{.Generated}}. Forecasts use recurring entries, detected subscriptions and average monthly spending per category.</p>
+<p class="legend"><span style="background:#e8f8ea">Actual - from stored entries</span><span style="background:#fff6dd;font-style:italic">Forecast - estimated</span></p>
+{{range .Accounts}}
+<h2>{{.Account}}</h2>
+<table>
+<tr><th>Date</th><th>Balance</th><th></th><th style="width:20em"></th></tr>
+{{$max := .MaxBalance}}
+{{range .Points}}
+<tr class="{{if .Forecast}}forecast{{else}}actual{{end}}">
+<td>{{.Date}}</td>
+<td class="amount{{if lt .Balance 0.0}} negative{{end}}">{{amount .Balance}}</td>
+<td class="kind">{{if .Forecast}}forecast{{else}}actual{{end}}</td>
+<td><div class="bar" style="width:{{barWidth .Balance $max}}%"></div></td>
+</tr>
+{{end}}
+</table>
+{{if .CategoryAverages}}
+<table>
+<tr><th>Category</th><th>Average per month</th></tr>
+{{range .CategoryAverages}}
+<tr><td>{{.Category}}</td><td class="amount{{if lt .Average 0.0}} negative{{end}}">{{amount .Average}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{else}}
+<p>No accounts to forecast.</p>
+{{end}}
+</body>
+</html>
+`))
+
+type forecastReportAccount struct {
+	accountForecast
+	MaxBalance float64 // largest absolute balance, scales the bars
+}
+
+type forecastReport struct {
+	Generated string
+	Accounts  []forecastReportAccount
+}
+
+// GET /reports/forecast
+func webForecastReport(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	forecasts, err := buildForecast(now)
+	if err != nil {
+		log.Printf("storage error: %v", err)
+		http.Error(w, "storage error", http.StatusInternalServerError)
+		return
+	}
+
+	report := forecastReport{Generated: now.Format("2006-01-02 15:04")}
+	for _, forecast := range forecasts {
+		account := forecastReportAccount{accountForecast: forecast}
+		for _, point := range forecast.Points {
+			account.MaxBalance = math.Max(account.MaxBalance, math.Abs(point.Balance))
+		}
+		report.Accounts = append(report.Accounts, account)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := forecast_report_template.Execute(w, report); err != nil {
+		log.Printf("error writing report: %v", err)
+	}
+}